@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"travelagency/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// restoreConfirmation has to be sent in the body, a restore replaces every change since the snapshot.
+const restoreConfirmation = "restore"
+
+type adminBackupRestoreHandler struct{}
+
+type adminBackupRestorePostBody struct {
+	Confirm string `json:"confirm"` //? must be "restore"
+}
+
+type adminBackupRestoreResponse struct {
+	Restored   string              `json:"restored"`
+	PreRestore repository.Snapshot `json:"preRestore"` //? the database as it was before the restore
+}
+
+func RespondAdminBackupRestore(writer http.ResponseWriter, request *http.Request) {
+	handler := adminBackupRestoreHandler{}
+	writeResponse(writer, handler.respond(request))
+}
+
+func (h *adminBackupRestoreHandler) respond(request *http.Request) APIResponse {
+	if response := requireAdmin(request); response != nil {
+		return *response
+	}
+
+	switch request.Method {
+
+	case http.MethodPost:
+		return h.handlePost(request)
+	default:
+		return InternalServerError([]byte("not implemented\n"))
+	}
+}
+
+func (h *adminBackupRestoreHandler) handlePost(request *http.Request) APIResponse {
+	name := mux.Vars(request)["name"]
+
+	var body adminBackupRestorePostBody
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		return decodeErrorResponse(err)
+	}
+
+	if body.Confirm != restoreConfirmation {
+		return BadRequestError([]byte(`confirm the restore with {"confirm": "restore"}` + "\n"))
+	}
+
+	var preRestore repository.Snapshot
+	err := duringMaintenance(func() (err error) {
+		preRestore, err = repository.RestoreDB(SnapshotDir, name)
+		return err
+	})
+
+	switch {
+	case errors.Is(err, repository.ErrSnapshotNotFound), errors.Is(err, os.ErrNotExist):
+		return DefaultNotFoundError()
+	case errors.Is(err, repository.ErrInvalidSnapshotName), errors.Is(err, repository.ErrInvalidSnapshot):
+		return UnprocessableEntityError([]byte(err.Error() + "\n"))
+	case err != nil:
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	Logger.LogAttrs(request.Context(), slog.LevelInfo, "database restored",
+		slog.String("requestId", requestIDFrom(request)),
+		slog.String("actor", actorName(request)),
+		slog.String("snapshot", name),
+		slog.String("preRestore", preRestore.Name),
+	)
+
+	jsonBody, _ := json.Marshal(adminBackupRestoreResponse{Restored: name, PreRestore: preRestore})
+	return OKContentType(jsonBody, ContentTypeJSON)
+}
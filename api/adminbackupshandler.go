@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"travelagency/repository"
+)
+
+// BackupRetention is how many backups are kept, older ones are removed after every backup. 0 keeps
+// every backup.
+var BackupRetention = 0
+
+type adminBackupsHandler struct{}
+
+func RespondAdminBackups(writer http.ResponseWriter, request *http.Request) {
+	handler := adminBackupsHandler{}
+	writeResponse(writer, handler.respond(request))
+}
+
+func (h *adminBackupsHandler) respond(request *http.Request) APIResponse {
+	if response := requireAdmin(request); response != nil {
+		return *response
+	}
+
+	switch request.Method {
+
+	case http.MethodGet:
+		return h.handleGet()
+	case http.MethodPost:
+		return h.handlePost(request)
+	default:
+		return InternalServerError([]byte("not implemented\n"))
+	}
+}
+
+func (h *adminBackupsHandler) handleGet() APIResponse {
+	snapshots, err := repository.ListSnapshots(SnapshotDir)
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	jsonBody, _ := json.Marshal(snapshots)
+	return OKContentType(jsonBody, ContentTypeJSON)
+}
+
+func (h *adminBackupsHandler) handlePost(request *http.Request) APIResponse {
+	snapshot, err := repository.BackupDB(SnapshotDir)
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	removed, err := repository.PruneBackups(SnapshotDir, BackupRetention)
+	if err != nil {
+		//? the backup itself succeeded, the next one prunes again
+		Logger.LogAttrs(request.Context(), slog.LevelError, "pruning backups",
+			slog.String("requestId", requestIDFrom(request)),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	Logger.LogAttrs(request.Context(), slog.LevelInfo, "database backup",
+		slog.String("requestId", requestIDFrom(request)),
+		slog.String("actor", actorName(request)),
+		slog.String("snapshot", snapshot.Name),
+		slog.Any("pruned", removed),
+	)
+
+	jsonBody, _ := json.Marshal(snapshot)
+	return Created(jsonBody, ContentTypeJSON, "/admin/backups")
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+type adminMaintenanceHandler struct{}
+
+type adminMaintenanceBody struct {
+	Enabled bool `json:"enabled"`
+}
+
+func RespondAdminMaintenance(writer http.ResponseWriter, request *http.Request) {
+	handler := adminMaintenanceHandler{}
+	writeResponse(writer, handler.respond(request))
+}
+
+func (h *adminMaintenanceHandler) respond(request *http.Request) APIResponse {
+	if response := requireAdmin(request); response != nil {
+		return *response
+	}
+
+	switch request.Method {
+
+	case http.MethodGet:
+		return h.handleGet()
+	case http.MethodPut:
+		return h.handlePut(request)
+	default:
+		return InternalServerError([]byte("not implemented\n"))
+	}
+}
+
+func (h *adminMaintenanceHandler) handleGet() APIResponse {
+	jsonBody, _ := json.Marshal(adminMaintenanceBody{Enabled: maintenance.enabled.Load()})
+	return OKContentType(jsonBody, ContentTypeJSON)
+}
+
+func (h *adminMaintenanceHandler) handlePut(request *http.Request) APIResponse {
+	var body adminMaintenanceBody
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		return decodeErrorResponse(err)
+	}
+
+	maintenance.enabled.Store(body.Enabled)
+
+	Logger.LogAttrs(request.Context(), slog.LevelInfo, "maintenance mode",
+		slog.String("requestId", requestIDFrom(request)),
+		slog.String("actor", actorName(request)),
+		slog.Bool("enabled", body.Enabled),
+	)
+
+	jsonBody, _ := json.Marshal(body)
+	return OKContentType(jsonBody, ContentTypeJSON)
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"travelagency/repository"
+)
+
+// ResetEnabled allows POST /admin/reset, it is off in the production environment.
+var ResetEnabled = true
+
+// SnapshotDir is where the database is copied before a reset.
+var SnapshotDir = "snapshots"
+
+// resetConfirmation has to be sent in the body so that a reset is never triggered by accident.
+const resetConfirmation = "reset"
+
+type adminResetHandler struct{}
+
+type adminResetPostBody struct {
+	Confirm string `json:"confirm"` //? must be "reset"
+	Seed    bool   `json:"seed"`    //? insert the sample locations, holidays and reservation
+}
+
+type adminResetResponse struct {
+	Snapshot string `json:"snapshot,omitempty"`
+	Seeded   bool   `json:"seeded"`
+}
+
+func RespondAdminReset(writer http.ResponseWriter, request *http.Request) {
+	handler := adminResetHandler{}
+	writeResponse(writer, handler.respond(request))
+}
+
+func (h *adminResetHandler) respond(request *http.Request) APIResponse {
+	if response := requireAdmin(request); response != nil {
+		return *response
+	}
+
+	switch request.Method {
+
+	case http.MethodPost:
+		return h.handlePost(request)
+	default:
+		return InternalServerError([]byte("not implemented\n"))
+	}
+}
+
+func (h *adminResetHandler) handlePost(request *http.Request) APIResponse {
+	if !ResetEnabled {
+		return ForbiddenError([]byte("the reset is disabled in production\n"))
+	}
+
+	var body adminResetPostBody
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		return decodeErrorResponse(err)
+	}
+
+	if body.Confirm != resetConfirmation {
+		return BadRequestError([]byte(`confirm the reset with {"confirm": "reset"}` + "\n"))
+	}
+
+	//? like a restore, the reset replaces the database under the running writes
+	var snapshot string
+	err := duringMaintenance(func() (err error) {
+		snapshot, err = repository.ResetDB(SnapshotDir, body.Seed)
+		return err
+	})
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	Logger.LogAttrs(request.Context(), slog.LevelInfo, "database reset",
+		slog.String("requestId", requestIDFrom(request)),
+		slog.String("actor", actorName(request)),
+		slog.String("snapshot", snapshot),
+	)
+
+	jsonBody, _ := json.Marshal(adminResetResponse{Snapshot: snapshot, Seeded: body.Seed})
+	return OKContentType(jsonBody, ContentTypeJSON)
+}
@@ -1,23 +1,95 @@
 package api
 
-import "net/http"
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"travelagency/repository"
+)
 
 type APIResponse struct {
-	Status      int     `json:"status,omitempty"`
-	Content     []byte  `json:"content,omitempty"`
-	ContentType *string `json:"contentType,omitempty"`
+	Status      int         `json:"status,omitempty"`
+	Content     []byte      `json:"content,omitempty"`
+	ContentType *string     `json:"contentType,omitempty"`
+	Header      http.Header `json:"header,omitempty"` //? extra headers written with the response
+}
+
+// writeResponse writes the status, headers and content of the response.
+func writeResponse(writer http.ResponseWriter, response APIResponse) {
+	for key, values := range response.Header {
+		//? middleware like CORS vary the response on request headers of their own
+		if key == "Vary" {
+			for _, value := range values {
+				writer.Header().Add(key, value)
+			}
+			continue
+		}
+
+		writer.Header()[key] = values
+	}
+
+	if response.ContentType != nil {
+		writer.Header().Set("Content-Type", *response.ContentType)
+	}
+
+	writer.WriteHeader(response.Status)
+	writer.Write(response.Content)
+}
+
+// WithHeader returns a copy of the response with the header set.
+func (response APIResponse) WithHeader(key string, value string) APIResponse {
+	header := response.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	header.Set(key, value)
+	response.Header = header
+	return response
 }
 
 var (
 	ContentTypeJSON = "application/json"
+	ContentTypeXML  = "application/xml"
 
 	ContentUnauthorized        = "Unauthorized\n"
+	ContentForbidden           = "Forbidden\n"
 	ContentOK                  = "OK\n"
 	ContentInternalServerError = "Internal Server Error\n"
 	ContentBadRequestError     = "Bad Request\n"
 	ContentNotFoundError       = "Not Found\n"
+	ContentNotImplementedError = "Not Implemented\n"
+	ContentNotAcceptableError  = "Not Acceptable, supported types are application/json, text/csv and application/xml\n"
+
+	ContentPreconditionFailedError   = "Precondition Failed\n"
+	ContentPreconditionRequiredError = "Precondition Required, send the ETag of the entity in If-Match\n"
 )
 
+func DefaultUnauthorizedError() APIResponse {
+	return UnauthorizedError([]byte(ContentUnauthorized))
+}
+
+func UnauthorizedError(content []byte) APIResponse {
+	return APIResponse{
+		Status:  http.StatusUnauthorized,
+		Content: content,
+	}
+}
+
+func DefaultForbiddenError() APIResponse {
+	return ForbiddenError([]byte(ContentForbidden))
+}
+
+func ForbiddenError(content []byte) APIResponse {
+	return APIResponse{
+		Status:  http.StatusForbidden,
+		Content: content,
+	}
+}
+
 func DefaultNotFoundError() APIResponse {
 	return NotFoundError([]byte(ContentNotFoundError))
 }
@@ -40,6 +112,99 @@ func InternalServerError(content []byte) APIResponse {
 	}
 }
 
+func DefaultNotImplementedError() APIResponse {
+	return NotImplementedError([]byte(ContentNotImplementedError))
+}
+
+func NotImplementedError(content []byte) APIResponse {
+	return APIResponse{
+		Status:  http.StatusNotImplemented,
+		Content: content,
+	}
+}
+
+func DefaultNotAcceptableError() APIResponse {
+	return NotAcceptableError([]byte(ContentNotAcceptableError))
+}
+
+func NotAcceptableError(content []byte) APIResponse {
+	return APIResponse{
+		Status:  http.StatusNotAcceptable,
+		Content: content,
+	}
+}
+
+func ConflictError(content []byte) APIResponse {
+	return APIResponse{
+		Status:  http.StatusConflict,
+		Content: content,
+	}
+}
+
+func UnprocessableEntityError(content []byte) APIResponse {
+	return APIResponse{
+		Status:  http.StatusUnprocessableEntity,
+		Content: content,
+	}
+}
+
+func PayloadTooLargeError(content []byte) APIResponse {
+	return APIResponse{
+		Status:  http.StatusRequestEntityTooLarge,
+		Content: content,
+	}
+}
+
+func TooManyRequestsError(content []byte) APIResponse {
+	return APIResponse{
+		Status:  http.StatusTooManyRequests,
+		Content: content,
+	}
+}
+
+func MethodNotAllowedError(content []byte) APIResponse {
+	return APIResponse{
+		Status:  http.StatusMethodNotAllowed,
+		Content: content,
+	}
+}
+
+func ServiceUnavailableError(content []byte) APIResponse {
+	return APIResponse{
+		Status:  http.StatusServiceUnavailable,
+		Content: content,
+	}
+}
+
+func UnsupportedMediaTypeError(content []byte) APIResponse {
+	return APIResponse{
+		Status:  http.StatusUnsupportedMediaType,
+		Content: content,
+	}
+}
+
+func DefaultPreconditionFailedError() APIResponse {
+	return PreconditionFailedError([]byte(ContentPreconditionFailedError))
+}
+
+func PreconditionFailedError(content []byte) APIResponse {
+	return APIResponse{
+		Status:  http.StatusPreconditionFailed,
+		Content: content,
+	}
+}
+
+func DefaultPreconditionRequiredError() APIResponse {
+	return PreconditionRequiredError([]byte(ContentPreconditionRequiredError))
+}
+
+func PreconditionRequiredError(content []byte) APIResponse {
+	return APIResponse{
+		Status:  http.StatusPreconditionRequired,
+		Content: content,
+	}
+}
+
 func DefaultBadRequestError() APIResponse {
 	return BadRequestError([]byte(ContentBadRequestError))
 }
@@ -63,9 +228,145 @@ func OKContentType(content []byte, contentType string) APIResponse {
 	}
 }
 
+// OKNegotiated returns the JSON body in the type the Accept header of the request prefers or a 406 when
+// the client accepts none of them.
+func OKNegotiated(request *http.Request, jsonBody []byte) APIResponse {
+	contentType, ok := negotiateContentType(request)
+	if !ok {
+		return DefaultNotAcceptableError()
+	}
+
+	return negotiatedContent(jsonBody, contentType)
+}
+
+// negotiatedContent encodes the JSON body as contentType, one of negotiableContentTypes.
+func negotiatedContent(jsonBody []byte, contentType string) APIResponse {
+	encode := responseEncoders[contentType]
+	content, err := encode(jsonBody)
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	return OKContentType(content, contentType).WithHeader("Vary", "Accept")
+}
+
+// negotiableContentTypes are the response types of list and detail endpoints in order of preference.
+var negotiableContentTypes = []string{ContentTypeJSON, ContentTypeCSV, ContentTypeXML, "text/xml"}
+
+// negotiateContentType picks the negotiable type with the highest quality in the Accept header of the request.
+// A request without Accept gets JSON, ok is false when the client accepts none of the types.
+func negotiateContentType(request *http.Request) (string, bool) {
+	header := strings.Join(request.Header.Values("Accept"), ",")
+	if strings.TrimSpace(header) == "" {
+		return ContentTypeJSON, true
+	}
+
+	ranges := strings.Split(header, ",")
+	best, bestQuality := "", 0.0
+	for _, contentType := range negotiableContentTypes {
+		quality := acceptQuality(ranges, contentType)
+		if quality > bestQuality {
+			best, bestQuality = contentType, quality
+		}
+	}
+
+	return best, best != ""
+}
+
+// acceptQuality returns the quality the most specific matching media range gives contentType, 0 when none matches.
+func acceptQuality(ranges []string, contentType string) float64 {
+	mainType, _, _ := strings.Cut(contentType, "/")
+
+	quality, specificity := 0.0, 0
+	for _, mediaRange := range ranges {
+		params := strings.Split(mediaRange, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+
+		matched := 0
+		switch name {
+		case contentType:
+			matched = 3
+		case mainType + "/*":
+			matched = 2
+		case "*/*":
+			matched = 1
+		}
+
+		if matched <= specificity {
+			continue
+		}
+
+		rangeQuality := 1.0
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				parsed, err := strconv.ParseFloat(value, 64)
+				if err == nil {
+					rangeQuality = parsed
+				}
+			}
+		}
+
+		quality, specificity = rangeQuality, matched
+	}
+
+	return quality
+}
+
+func NotModified() APIResponse {
+	return APIResponse{
+		Status: http.StatusNotModified,
+	}
+}
+
+func Created(content []byte, contentType string, location string) APIResponse {
+	return APIResponse{
+		Status:      http.StatusCreated,
+		Content:     content,
+		ContentType: &contentType,
+		Header:      http.Header{"Location": []string{location}},
+	}
+}
+
 func OK(content []byte) APIResponse {
 	return APIResponse{
 		Status:  http.StatusOK,
 		Content: content,
 	}
 }
+
+// repositoryErrorResponse maps the errors of changing entities to responses.
+func repositoryErrorResponse(err error) APIResponse {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return DefaultNotFoundError()
+	case errors.Is(err, repository.ErrLocationHasHolidays),
+		errors.Is(err, repository.ErrHolidayHasReservations),
+		errors.Is(err, repository.ErrCategoryHasHolidays),
+		errors.Is(err, repository.ErrLocationDeleted),
+		errors.Is(err, repository.ErrHolidayDeleted):
+		return ConflictError([]byte(err.Error()))
+	case errors.Is(err, repository.ErrVersionMismatch):
+		return PreconditionFailedError([]byte(err.Error()))
+	default:
+		return InternalServerError([]byte(err.Error()))
+	}
+}
+
+// decodeErrorResponse maps the errors of decoding a request body to responses, 413 when LimitBody
+// cut the body off and 400 otherwise.
+func decodeErrorResponse(err error) APIResponse {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return PayloadTooLargeError([]byte(fmt.Sprintf("the body is limited to %d bytes\n", maxBytesErr.Limit)))
+	}
+
+	return BadRequestError([]byte(err.Error()))
+}
+
+// deprecated marks the response of a legacy route and points clients at the route that replaces it.
+func deprecated(response APIResponse, successor string) APIResponse {
+	return response.
+		WithHeader("Deprecation", "true").
+		WithHeader("Link", "<"+successor+`>; rel="successor-version"`)
+}
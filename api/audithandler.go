@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"travelagency/repository"
+)
+
+type auditHandler struct {
+	auditRepo *repository.AuditRepo
+}
+
+func RespondAudit(writer http.ResponseWriter, request *http.Request) {
+	auditRepo, err := repository.NewAuditRepo(nil)
+	if err != nil {
+		response := InternalServerError([]byte("couldn't connect to database"))
+
+		writer.WriteHeader(response.Status)
+		writer.Write(response.Content)
+		return
+	}
+
+	auditRepo.SetContext(request.Context())
+
+	handler := auditHandler{
+		auditRepo: auditRepo,
+	}
+
+	writeResponse(writer, handler.respond(request))
+}
+
+func (h *auditHandler) respond(request *http.Request) APIResponse {
+	if response := requireAdmin(request); response != nil {
+		return *response
+	}
+
+	switch request.Method {
+
+	case http.MethodGet:
+		return h.handleGet(request)
+	default:
+		return InternalServerError([]byte("not implemented\n"))
+	}
+}
+
+func (h *auditHandler) handleGet(request *http.Request) APIResponse {
+	query := request.URL.Query()
+	filter := repository.AuditFilter{
+		EntityType: query.Get("entity"),
+		Actor:      query.Get("actor"),
+	}
+
+	if filter.EntityType != "" && !repository.IsAuditEntity(filter.EntityType) {
+		return BadRequestError([]byte("invalid entity"))
+	}
+
+	if idStr := query.Get("id"); idStr != "" {
+		if filter.EntityType == "" {
+			return BadRequestError([]byte("id requires entity"))
+		}
+
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			return BadRequestError([]byte("invalid id"))
+		}
+
+		filter.EntityId = id
+	}
+
+	data, err := h.auditRepo.GetAll(filter)
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	jsonBody, _ := json.Marshal(data)
+	return OKNegotiated(request, jsonBody)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+const (
+	RoleAdmin = "admin"
+	RoleAgent = "agent"
+
+	anonymousActor = "anonymous"
+)
+
+type Principal struct {
+	Name string
+	Role string
+}
+
+// APIKeys maps API keys to the principals using them. It is set from the `auth.apiKeys`
+// configuration in the format `key:name:role,key:name:role`.
+var APIKeys = map[string]Principal{}
+
+func ParseAPIKeys(value string) map[string]Principal {
+	keys := map[string]Principal{}
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), ":")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+			continue
+		}
+
+		keys[parts[0]] = Principal{
+			Name: parts[1],
+			Role: parts[2],
+		}
+	}
+
+	return keys
+}
+
+// authenticate returns the principal of the `X-API-Key` header or nil for anonymous requests.
+func authenticate(request *http.Request) *Principal {
+	key := request.Header.Get("X-API-Key")
+	if key == "" {
+		return nil
+	}
+
+	for candidate, principal := range APIKeys {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(key)) == 1 {
+			return &principal
+		}
+	}
+
+	return nil
+}
+
+// actorName returns who is recorded in the audit log for the request.
+func actorName(request *http.Request) string {
+	principal := authenticate(request)
+	if principal == nil {
+		return anonymousActor
+	}
+
+	return principal.Name
+}
+
+// isAdmin reports whether the request is made with an admin key.
+func isAdmin(request *http.Request) bool {
+	principal := authenticate(request)
+	return principal != nil && principal.Role == RoleAdmin
+}
+
+// requireAdmin returns an error response unless the request is made with an admin key.
+func requireAdmin(request *http.Request) *APIResponse {
+	principal := authenticate(request)
+	if principal == nil {
+		response := DefaultUnauthorizedError()
+		return &response
+	}
+
+	if principal.Role != RoleAdmin {
+		response := DefaultForbiddenError()
+		return &response
+	}
+
+	return nil
+}
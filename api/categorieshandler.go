@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"travelagency/repository"
+)
+
+type categoriesHandler struct {
+	categoriesRepo *repository.CategoriesRepo
+}
+
+type categoryHandlerPostBody struct {
+	Name string `json:"name"`
+}
+
+type categoryHandlerPutBody struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func RespondCategories(writer http.ResponseWriter, request *http.Request) {
+	categoriesRepo, err := repository.NewCategoriesRepo(nil)
+	if err != nil {
+		response := InternalServerError([]byte("couldn't connect to database"))
+
+		writer.WriteHeader(response.Status)
+		writer.Write(response.Content)
+		return
+	}
+
+	actor := actorName(request)
+	categoriesRepo.SetActor(actor)
+	categoriesRepo.SetContext(request.Context())
+
+	handler := categoriesHandler{
+		categoriesRepo: categoriesRepo,
+	}
+
+	writeResponse(writer, handler.respond(request))
+}
+
+func (h *categoriesHandler) respond(request *http.Request) APIResponse {
+	switch request.Method {
+
+	case http.MethodGet:
+		return h.handleGet(request)
+	case http.MethodPost:
+		return h.handlePost(request)
+	case http.MethodPut:
+		return h.handlePut(request)
+	default:
+		return InternalServerError([]byte("not implemented\n"))
+	}
+}
+
+func (h *categoriesHandler) handlePost(request *http.Request) APIResponse {
+	var body categoryHandlerPostBody
+	err := json.NewDecoder(request.Body).Decode(&body)
+	if err != nil {
+		return decodeErrorResponse(err)
+	}
+
+	name := strings.TrimSpace(body.Name)
+	if name == "" {
+		return BadRequestError([]byte("invalid category name"))
+	}
+
+	entity, err := h.categoriesRepo.Insert(repository.CategoriesEntity{
+		Name: name,
+	})
+
+	if err != nil {
+		return repositoryErrorResponse(err)
+	}
+
+	jsonBody, _ := json.Marshal(entity)
+	return Created(jsonBody, ContentTypeJSON, "/categories/"+strconv.FormatInt(entity.ID, 10))
+}
+
+// handlePut updates the category with the id in the body, it is kept for old clients and replaced by PUT /categories/{id}.
+func (h *categoriesHandler) handlePut(request *http.Request) APIResponse {
+	var body categoryHandlerPutBody
+	err := json.NewDecoder(request.Body).Decode(&body)
+	if err != nil {
+		return decodeErrorResponse(err)
+	}
+
+	if body.ID == 0 {
+		return BadRequestError([]byte("invalid category id"))
+	}
+
+	response := putCategory(h.categoriesRepo, request, body)
+	return deprecated(response, "/categories/"+strconv.FormatInt(body.ID, 10))
+}
+
+// putCategory replaces every field of the category with the body.
+func putCategory(categoriesRepo *repository.CategoriesRepo, request *http.Request, body categoryHandlerPutBody) APIResponse {
+	name := strings.TrimSpace(body.Name)
+	if name == "" {
+		return BadRequestError([]byte("invalid category name"))
+	}
+
+	entity, err := categoriesRepo.Update(repository.CategoriesEntity{
+		ID:   body.ID,
+		Name: name,
+	})
+
+	if err != nil {
+		return repositoryErrorResponse(err)
+	}
+
+	jsonBody, _ := json.Marshal(entity)
+	return OKContentType(jsonBody, ContentTypeJSON)
+}
+
+func (h *categoriesHandler) handleGet(request *http.Request) APIResponse {
+	data, err := h.categoriesRepo.GetAll()
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	jsonBody, _ := json.Marshal(data)
+	return OKNegotiated(request, jsonBody)
+}
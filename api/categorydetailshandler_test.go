@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCategoryAndTagChangesOfMissingIDsAnswerNotFound(t *testing.T) {
+	router := useFixtures(t)
+
+	for _, path := range []string{"/categories/999", "/tags/999"} {
+		if response := serveRequest(router, http.MethodPut, path, `{"name": "cruises"}`, map[string]string{"Content-Type": ContentTypeJSON}); response.Code != http.StatusNotFound {
+			t.Errorf("PUT %s answered %d: %s", path, response.Code, response.Body.String())
+		}
+
+		if response := serveRequest(router, http.MethodDelete, path, "", nil); response.Code != http.StatusNotFound {
+			t.Errorf("DELETE %s answered %d: %s", path, response.Code, response.Body.String())
+		}
+	}
+
+	if response := serveRequest(router, http.MethodPut, "/categories", `{"id": 999, "name": "Cruises"}`, map[string]string{"Content-Type": ContentTypeJSON}); response.Code != http.StatusNotFound {
+		t.Errorf("the legacy PUT /categories answered %d: %s", response.Code, response.Body.String())
+	}
+}
+
+func TestDeletingACategoryInUseAnswersConflict(t *testing.T) {
+	router := useFixtures(t)
+
+	if response := serveRequest(router, http.MethodDelete, "/categories/2", "", nil); response.Code != http.StatusConflict {
+		t.Errorf("deleting a category of a holiday answered %d: %s", response.Code, response.Body.String())
+	}
+
+	//? a soft deleted holiday still refers to its category
+	holiday := serveRequest(router, http.MethodGet, "/holidays/3", "", nil)
+	if response := serveRequest(router, http.MethodDelete, "/holidays/3", "", map[string]string{"If-Match": holiday.Header().Get("ETag")}); response.Code != http.StatusOK {
+		t.Fatalf("deleting the holiday answered %d: %s", response.Code, response.Body.String())
+	}
+
+	if response := serveRequest(router, http.MethodDelete, "/categories/3", "", nil); response.Code != http.StatusConflict {
+		t.Errorf("deleting the category of a deleted holiday answered %d: %s", response.Code, response.Body.String())
+	}
+
+	created := serveRequest(router, http.MethodPost, "/categories", `{"name": "Cruises"}`, map[string]string{"Content-Type": ContentTypeJSON})
+	if created.Code != http.StatusCreated {
+		t.Fatalf("creating a category answered %d", created.Code)
+	}
+
+	if response := serveRequest(router, http.MethodDelete, created.Header().Get("Location"), "", nil); response.Code != http.StatusOK {
+		t.Errorf("deleting an unused category answered %d: %s", response.Code, response.Body.String())
+	}
+
+	//? the tags of holidays are removed with the tag
+	if response := serveRequest(router, http.MethodDelete, "/tags/1", "", nil); response.Code != http.StatusOK {
+		t.Errorf("deleting a tag of holidays answered %d: %s", response.Code, response.Body.String())
+	}
+}
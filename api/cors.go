@@ -0,0 +1,170 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CrossOriginPolicy decides which browser origins may call the API.
+type CrossOriginPolicy struct {
+	AllowedOrigins   []string //? `*`, exact origins or origins with a wildcard subdomain like `https://*.example.com`
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// CORSPolicy is set from the `cors` configuration, no origin is allowed by default.
+var CORSPolicy CrossOriginPolicy
+
+// exposedHeaders are the response headers scripts of other origins may read.
+var exposedHeaders = []string{
+	"ETag", "Location", "Link", "Deprecation", "Idempotent-Replayed", requestIDHeader,
+	"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After",
+}
+
+// CORS adds the CORS headers for allowed origins and answers `OPTIONS` requests, preflights included,
+// before they reach the handlers.
+func CORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		origin := request.Header.Get("Origin")
+		preflight := request.Method == http.MethodOptions && origin != "" && request.Header.Get("Access-Control-Request-Method") != ""
+
+		if origin != "" {
+			writer.Header().Add("Vary", "Origin")
+		}
+
+		if preflight {
+			writer.Header().Add("Vary", "Access-Control-Request-Method")
+			writer.Header().Add("Vary", "Access-Control-Request-Headers")
+			writeResponse(writer, CORSPolicy.preflight(request, origin))
+			return
+		}
+
+		if origin != "" && CORSPolicy.allowsOrigin(origin) {
+			writer.Header().Set("Access-Control-Allow-Origin", CORSPolicy.allowOriginValue(origin))
+			writer.Header().Set("Access-Control-Expose-Headers", strings.Join(exposedHeaders, ", "))
+			if CORSPolicy.AllowCredentials {
+				writer.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		//? the handlers only know the methods they implement, answer OPTIONS from the documented routes
+		if request.Method == http.MethodOptions {
+			writer.Header().Set("Allow", strings.Join(append(routeMethods(routeTemplate(request)), http.MethodOptions), ", "))
+			writer.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// preflight answers whether the browser may send the request described by the preflight headers.
+func (policy CrossOriginPolicy) preflight(request *http.Request, origin string) APIResponse {
+	if !policy.allowsOrigin(origin) {
+		return ForbiddenError([]byte("origin not allowed\n"))
+	}
+
+	method := request.Header.Get("Access-Control-Request-Method")
+	if !containsFold(policy.AllowedMethods, method) {
+		return ForbiddenError([]byte("method not allowed for cross-origin requests\n"))
+	}
+
+	var headers []string
+	for _, header := range strings.Split(request.Header.Get("Access-Control-Request-Headers"), ",") {
+		if header = strings.TrimSpace(header); header == "" {
+			continue
+		}
+
+		if !containsFold(policy.AllowedHeaders, header) && !containsFold(policy.AllowedHeaders, "*") {
+			return ForbiddenError([]byte("header " + header + " not allowed for cross-origin requests\n"))
+		}
+
+		headers = append(headers, header)
+	}
+
+	response := APIResponse{Status: http.StatusNoContent}.
+		WithHeader("Access-Control-Allow-Origin", policy.allowOriginValue(origin)).
+		WithHeader("Access-Control-Allow-Methods", strings.Join(policy.AllowedMethods, ", "))
+
+	if len(headers) > 0 {
+		response = response.WithHeader("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+
+	if policy.AllowCredentials {
+		response = response.WithHeader("Access-Control-Allow-Credentials", "true")
+	}
+
+	if policy.MaxAge > 0 {
+		response = response.WithHeader("Access-Control-Max-Age", strconv.Itoa(int(policy.MaxAge.Seconds())))
+	}
+
+	return response
+}
+
+func (policy CrossOriginPolicy) allowsOrigin(origin string) bool {
+	for _, allowed := range policy.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) || matchesWildcardOrigin(allowed, origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowOriginValue echoes the origin unless every origin is allowed without credentials.
+func (policy CrossOriginPolicy) allowOriginValue(origin string) string {
+	for _, allowed := range policy.AllowedOrigins {
+		if allowed == "*" && !policy.AllowCredentials {
+			return "*"
+		}
+	}
+
+	return origin
+}
+
+// matchesWildcardOrigin matches `https://*.example.com` with the origins of its subdomains at any
+// depth, but not with `https://example.com` itself.
+func matchesWildcardOrigin(pattern string, origin string) bool {
+	scheme, domain, found := strings.Cut(pattern, "*.")
+	if !found {
+		return false
+	}
+
+	origin = strings.ToLower(origin)
+	if !strings.HasPrefix(origin, strings.ToLower(scheme)) || !strings.HasSuffix(origin, "."+strings.ToLower(domain)) {
+		return false
+	}
+
+	subdomain := origin[len(scheme) : len(origin)-len(domain)-1]
+	return subdomain != "" && !strings.ContainsAny(subdomain, "/:@")
+}
+
+// routeMethods returns the methods documented for the route template.
+func routeMethods(template string) []string {
+	var methods []string
+	for _, route := range Routes {
+		if openAPIPath(route.Path) != openAPIPath(template) {
+			continue
+		}
+
+		for _, operation := range route.Operations {
+			methods = append(methods, operation.Method)
+		}
+	}
+
+	return methods
+}
+
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// useCORSPolicy sets the policy for the test and returns a router with the CORS middleware whose
+// handlers answer 200.
+func useCORSPolicy(t *testing.T, policy CrossOriginPolicy) *mux.Router {
+	t.Helper()
+
+	previous := CORSPolicy
+	CORSPolicy = policy
+	t.Cleanup(func() { CORSPolicy = previous })
+
+	router := mux.NewRouter()
+	router.Use(CORS)
+	router.Handle("/holidays", http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+	})).Methods(http.MethodGet, http.MethodPost, http.MethodOptions)
+
+	return router
+}
+
+func serveCORS(router *mux.Router, method string, header map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, "/holidays", nil)
+	for name, value := range header {
+		request.Header.Set(name, value)
+	}
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	return response
+}
+
+func TestCORSMatchesOrigins(t *testing.T) {
+	router := useCORSPolicy(t, CrossOriginPolicy{
+		AllowedOrigins: []string{"https://travel.example", "https://*.agency.example"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPost},
+	})
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://travel.example", true},
+		{"HTTPS://TRAVEL.EXAMPLE", true},
+		{"https://shop.agency.example", true},
+		{"https://eu.shop.agency.example", true},
+		{"https://agency.example", false},
+		{"http://shop.agency.example", false},
+		{"https://travel.example.evil", false},
+		{"https://evil.example/.agency.example", false},
+	}
+
+	for _, test := range tests {
+		response := serveCORS(router, http.MethodGet, map[string]string{"Origin": test.origin})
+		if response.Code != http.StatusOK {
+			t.Errorf("GET from %s answered %d", test.origin, response.Code)
+		}
+
+		allowOrigin := response.Header().Get("Access-Control-Allow-Origin")
+		if test.allowed && allowOrigin != test.origin {
+			t.Errorf("%s is allowed as %q", test.origin, allowOrigin)
+		}
+
+		if !test.allowed && allowOrigin != "" {
+			t.Errorf("%s is not allowed but got %q", test.origin, allowOrigin)
+		}
+
+		//? caches must not serve the response of one origin to another
+		if !strings.Contains(strings.Join(response.Header().Values("Vary"), ","), "Origin") {
+			t.Errorf("the response to %s does not vary by Origin", test.origin)
+		}
+	}
+
+	if response := serveCORS(router, http.MethodGet, nil); response.Header().Get("Access-Control-Allow-Origin") != "" || len(response.Header().Values("Vary")) != 0 {
+		t.Errorf("a same-origin request got CORS headers: %v", response.Header())
+	}
+}
+
+func TestCORSAnswersPreflightsBeforeTheHandlers(t *testing.T) {
+	router := useCORSPolicy(t, CrossOriginPolicy{
+		AllowedOrigins: []string{"https://travel.example"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders: []string{"Content-Type", "X-API-Key"},
+		MaxAge:         10 * time.Minute,
+	})
+
+	preflight := func(origin string, method string, headers string) *httptest.ResponseRecorder {
+		return serveCORS(router, http.MethodOptions, map[string]string{
+			"Origin":                         origin,
+			"Access-Control-Request-Method":  method,
+			"Access-Control-Request-Headers": headers,
+		})
+	}
+
+	response := preflight("https://travel.example", http.MethodPost, "content-type, x-api-key")
+	if response.Code != http.StatusNoContent {
+		t.Fatalf("the preflight answered %d, the handler was reached", response.Code)
+	}
+
+	expected := map[string]string{
+		"Access-Control-Allow-Origin":  "https://travel.example",
+		"Access-Control-Allow-Methods": "GET, POST",
+		"Access-Control-Allow-Headers": "content-type, x-api-key",
+		"Access-Control-Max-Age":       "600",
+	}
+	for name, value := range expected {
+		if got := response.Header().Get(name); got != value {
+			t.Errorf("%s is %q, expected %q", name, got, value)
+		}
+	}
+
+	vary := strings.Join(response.Header().Values("Vary"), ",")
+	for _, name := range []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"} {
+		if !strings.Contains(vary, name) {
+			t.Errorf("the preflight does not vary by %s", name)
+		}
+	}
+
+	rejected := []struct {
+		name    string
+		origin  string
+		method  string
+		headers string
+	}{
+		{"origin", "https://evil.example", http.MethodGet, ""},
+		{"method", "https://travel.example", http.MethodDelete, ""},
+		{"header", "https://travel.example", http.MethodPost, "X-Debug"},
+	}
+	for _, test := range rejected {
+		if response := preflight(test.origin, test.method, test.headers); response.Code != http.StatusForbidden {
+			t.Errorf("a preflight with a disallowed %s answered %d", test.name, response.Code)
+		}
+	}
+}
+
+func TestCORSNeverAllowsCredentialsForEveryOrigin(t *testing.T) {
+	router := useCORSPolicy(t, CrossOriginPolicy{AllowedOrigins: []string{"*"}, AllowedMethods: []string{http.MethodGet}})
+
+	if response := serveCORS(router, http.MethodGet, map[string]string{"Origin": "https://travel.example"}); response.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("every origin without credentials is allowed as %q", response.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	//? browsers reject `*` with credentials, the origin is echoed instead
+	router = useCORSPolicy(t, CrossOriginPolicy{AllowedOrigins: []string{"*"}, AllowedMethods: []string{http.MethodGet}, AllowCredentials: true})
+
+	response := serveCORS(router, http.MethodGet, map[string]string{"Origin": "https://travel.example"})
+	if got := response.Header().Get("Access-Control-Allow-Origin"); got != "https://travel.example" {
+		t.Errorf("every origin with credentials is allowed as %q", got)
+	}
+
+	if response.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Error("the credentials are not allowed")
+	}
+}
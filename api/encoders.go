@@ -0,0 +1,283 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// responseEncoders turn the JSON body of a response into each of the negotiableContentTypes.
+// Encoding from JSON keeps the field names and order of every type the same as in JSON.
+var responseEncoders = map[string]func(jsonBody []byte) ([]byte, error){
+	ContentTypeJSON: func(jsonBody []byte) ([]byte, error) { return jsonBody, nil },
+	ContentTypeCSV:  encodeCSV,
+	ContentTypeXML:  encodeXML,
+	"text/xml":      encodeXML,
+}
+
+// jsonNode is a decoded JSON value that keeps the order of object keys.
+type jsonNode struct {
+	keys   []string //? object keys in document order, nil for arrays and scalars
+	fields map[string]*jsonNode
+	items  []*jsonNode
+	value  any //? string, json.Number, bool or nil for scalars
+	isList bool
+}
+
+func (node *jsonNode) isObject() bool {
+	return node.fields != nil
+}
+
+func (node *jsonNode) isScalar() bool {
+	return !node.isObject() && !node.isList
+}
+
+// text returns a scalar as it is written in CSV cells and XML elements, null is empty.
+func (node *jsonNode) text() string {
+	switch value := node.value.(type) {
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	case bool:
+		if value {
+			return "true"
+		}
+		return "false"
+	default:
+		return ""
+	}
+}
+
+func parseJSONNode(jsonBody []byte) (*jsonNode, error) {
+	decoder := json.NewDecoder(bytes.NewReader(jsonBody))
+	decoder.UseNumber()
+	return readJSONNode(decoder)
+}
+
+func readJSONNode(decoder *json.Decoder) (*jsonNode, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		node := &jsonNode{fields: map[string]*jsonNode{}}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := readJSONNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+
+			node.keys = append(node.keys, key.(string))
+			node.fields[key.(string)] = value
+		}
+
+		_, err = decoder.Token()
+		return node, err
+	case json.Delim('['):
+		node := &jsonNode{isList: true}
+		for decoder.More() {
+			item, err := readJSONNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+
+			node.items = append(node.items, item)
+		}
+
+		_, err = decoder.Token()
+		return node, err
+	default:
+		return &jsonNode{value: token}, nil
+	}
+}
+
+// encodeCSV writes a list as one row per item and anything else as a single row. Nested objects are
+// flattened into dotted columns like holiday.location.city, lists of scalars are joined with ; and lists
+// of objects get the index in the column like images.0.url.
+func encodeCSV(jsonBody []byte) ([]byte, error) {
+	root, err := parseJSONNode(jsonBody)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []*jsonNode{root}
+	if root.isList {
+		items = root.items
+	}
+
+	columns := []string{}
+	seen := map[string]bool{}
+	rows := []map[string]string{}
+	for _, item := range items {
+		row := map[string]string{}
+		flattenCSV(item, "", row, func(column string) {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		})
+		rows = append(rows, row)
+	}
+
+	var buffer bytes.Buffer
+	if len(columns) == 0 {
+		return buffer.Bytes(), nil
+	}
+
+	writer := csv.NewWriter(&buffer)
+	writer.Write(columns)
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i] = row[column]
+		}
+		writer.Write(record)
+	}
+
+	writer.Flush()
+	return buffer.Bytes(), writer.Error()
+}
+
+func flattenCSV(node *jsonNode, column string, row map[string]string, addColumn func(column string)) {
+	switch {
+	case node.isObject():
+		for _, key := range node.keys {
+			flattenCSV(node.fields[key], joinColumn(column, key), row, addColumn)
+		}
+	case node.isList && allScalars(node.items):
+		values := make([]string, len(node.items))
+		for i, item := range node.items {
+			values[i] = item.text()
+		}
+
+		addColumn(csvColumn(column))
+		row[csvColumn(column)] = strings.Join(values, ";")
+	case node.isList:
+		for i, item := range node.items {
+			flattenCSV(item, joinColumn(column, strconv.Itoa(i)), row, addColumn)
+		}
+	default:
+		addColumn(csvColumn(column))
+		row[csvColumn(column)] = node.text()
+	}
+}
+
+func joinColumn(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + "." + key
+}
+
+// csvColumn names the column of a body that is a single scalar, it still needs a header.
+func csvColumn(column string) string {
+	if column == "" {
+		return "value"
+	}
+
+	return column
+}
+
+func allScalars(nodes []*jsonNode) bool {
+	for _, node := range nodes {
+		if !node.isScalar() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// encodeXML writes the body under a <response> root. Object keys become elements, list items become
+// <item> elements and keys that are no valid element name, like facet values, become <entry key="...">.
+func encodeXML(jsonBody []byte) ([]byte, error) {
+	root, err := parseJSONNode(jsonBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString(xml.Header)
+
+	encoder := xml.NewEncoder(&buffer)
+	if err := writeXMLNode(encoder, root, xml.StartElement{Name: xml.Name{Local: "response"}}); err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Flush(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func writeXMLNode(encoder *xml.Encoder, node *jsonNode, start xml.StartElement) error {
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch {
+	case node.isObject():
+		for _, key := range node.keys {
+			if err := writeXMLNode(encoder, node.fields[key], xmlElement(key)); err != nil {
+				return err
+			}
+		}
+	case node.isList:
+		for _, item := range node.items {
+			if err := writeXMLNode(encoder, item, xml.StartElement{Name: xml.Name{Local: "item"}}); err != nil {
+				return err
+			}
+		}
+	default:
+		if err := encoder.EncodeToken(xml.CharData(node.text())); err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
+
+func xmlElement(key string) xml.StartElement {
+	if validXMLName(key) {
+		return xml.StartElement{Name: xml.Name{Local: key}}
+	}
+
+	return xml.StartElement{
+		Name: xml.Name{Local: "entry"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: key}},
+	}
+}
+
+func validXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+
+	for i, r := range name {
+		if r == '_' || unicode.IsLetter(r) {
+			continue
+		}
+
+		if i > 0 && (r == '-' || r == '.' || unicode.IsDigit(r)) {
+			continue
+		}
+
+		return false
+	}
+
+	return true
+}
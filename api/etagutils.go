@@ -0,0 +1,105 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// etag returns the strong entity tag of a representation: the version of the entity, which If-Match
+// compares, and a hash of the content type and content, which differs between the representations and
+// also changes with the entities embedded in them.
+func etag(version int64, contentType string, content []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, contentType+"\n")
+	hash.Write(content)
+	return `"` + strconv.FormatInt(version, 10) + "-" + hex.EncodeToString(hash.Sum(nil)[:8]) + `"`
+}
+
+// etagVersion returns the entity version of a tag made by etag. Tags of only the version, the
+// format before the hash was added, still parse.
+func etagVersion(tag string) (int64, bool) {
+	opaque := strings.Trim(tag, `"`)
+	version, _, _ := strings.Cut(opaque, "-")
+	parsed, err := strconv.ParseInt(version, 10, 64)
+	return parsed, err == nil
+}
+
+// etagMatches reports whether one of the comma separated entity tags of a conditional header matches.
+// Weak tags only match when weak comparison is allowed, as it is for If-None-Match.
+func etagMatches(header string, weak bool, matches func(tag string) bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+
+		if matches(tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkIfMatch requires an If-Match header matching the current version before an entity is changed.
+func checkIfMatch(request *http.Request, version int64) *APIResponse {
+	header := request.Header.Get("If-Match")
+	if header == "" {
+		response := DefaultPreconditionRequiredError()
+		return &response
+	}
+
+	//? changes are made to the entity, not to a representation, only its version has to match
+	matchesVersion := func(tag string) bool {
+		tagVersion, ok := etagVersion(tag)
+		return ok && tagVersion == version
+	}
+
+	if !etagMatches(header, false, matchesVersion) {
+		response := DefaultPreconditionFailedError()
+		return &response
+	}
+
+	return nil
+}
+
+// notModified reports whether the If-None-Match header of the request matches the current tag.
+func notModified(request *http.Request, currentTag string) bool {
+	header := request.Header.Get("If-None-Match")
+	return header != "" && etagMatches(header, true, func(tag string) bool { return tag == currentTag })
+}
+
+// entityResponse returns the entity with its ETag or a 304 when the client already has this representation.
+// GET negotiates the type of the entity, changes fall back to JSON rather than fail after they are done.
+func entityResponse(request *http.Request, jsonBody []byte, version int64) APIResponse {
+	contentType, ok := negotiateContentType(request)
+	if request.Method == http.MethodGet && !ok {
+		return DefaultNotAcceptableError()
+	} else if !ok {
+		contentType = ContentTypeJSON
+	}
+
+	response := negotiatedContent(jsonBody, contentType)
+	if response.Status != http.StatusOK {
+		return response
+	}
+
+	tag := etag(version, contentType, response.Content)
+	if request.Method == http.MethodGet && notModified(request, tag) {
+		return NotModified().WithHeader("ETag", tag).WithHeader("Vary", "Accept")
+	}
+
+	return response.WithHeader("ETag", tag)
+}
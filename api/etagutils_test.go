@@ -0,0 +1,111 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"travelagency/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// useFixtures seeds the sample locations, holidays and reservation into the test database.
+func useFixtures(t *testing.T) *mux.Router {
+	t.Helper()
+
+	useTestDB(t)
+	useAdminKey(t)
+	if _, err := repository.ResetDB(t.TempDir(), true); err != nil {
+		t.Fatalf("seeding: %v", err)
+	}
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, allFeatures)
+	return router
+}
+
+func serveRequest(router *mux.Router, method string, target string, body string, header map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	request.Header.Set("X-API-Key", "adm")
+	for name, value := range header {
+		request.Header.Set(name, value)
+	}
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	return response
+}
+
+func TestHolidayETagChangesWithTheEmbeddedLocation(t *testing.T) {
+	router := useFixtures(t)
+
+	holiday := serveRequest(router, http.MethodGet, "/holidays/2", "", nil)
+	holidayTag := holiday.Header().Get("ETag")
+	if holiday.Code != http.StatusOK || holidayTag == "" {
+		t.Fatalf("GET /holidays/2 answered %d with the ETag %q", holiday.Code, holidayTag)
+	}
+
+	if cached := serveRequest(router, http.MethodGet, "/holidays/2", "", map[string]string{"If-None-Match": holidayTag}); cached.Code != http.StatusNotModified {
+		t.Fatalf("the unchanged holiday answered %d", cached.Code)
+	}
+
+	location := serveRequest(router, http.MethodGet, "/locations/2", "", nil)
+	patched := serveRequest(router, http.MethodPatch, "/locations/2", `{"city": "Lisbon Baixa"}`, map[string]string{
+		"Content-Type": ContentTypeMergePatch,
+		"If-Match":     location.Header().Get("ETag"),
+	})
+	if patched.Code != http.StatusOK {
+		t.Fatalf("patching the location answered %d: %s", patched.Code, patched.Body.String())
+	}
+
+	//? the holiday row did not change, its body did
+	changed := serveRequest(router, http.MethodGet, "/holidays/2", "", map[string]string{"If-None-Match": holidayTag})
+	if changed.Code != http.StatusOK {
+		t.Fatalf("the holiday with a changed location answered %d", changed.Code)
+	}
+
+	if changed.Header().Get("ETag") == holidayTag {
+		t.Errorf("the ETag %s did not change with the location", holidayTag)
+	}
+
+	//? If-Match compares the version of the holiday, which the tag before the location change still has
+	deleted := serveRequest(router, http.MethodDelete, "/holidays/2", "", map[string]string{"If-Match": holidayTag})
+	if deleted.Code != http.StatusOK {
+		t.Errorf("deleting with the tag of the same holiday version answered %d: %s", deleted.Code, deleted.Body.String())
+	}
+}
+
+func TestEveryRepresentationHasItsOwnETag(t *testing.T) {
+	router := useFixtures(t)
+
+	tags := map[string]string{}
+	for _, accept := range []string{ContentTypeJSON, ContentTypeCSV, ContentTypeXML, "text/xml"} {
+		response := serveRequest(router, http.MethodGet, "/holidays/2", "", map[string]string{"Accept": accept})
+		if response.Code != http.StatusOK {
+			t.Fatalf("GET /holidays/2 as %s answered %d", accept, response.Code)
+		}
+
+		if !strings.Contains(strings.Join(response.Header().Values("Vary"), ","), "Accept") {
+			t.Errorf("the %s representation does not vary by Accept", accept)
+		}
+
+		tag := response.Header().Get("ETag")
+		if other, exists := tags[tag]; exists {
+			t.Errorf("%s and %s share the ETag %s", other, accept, tag)
+		}
+		tags[tag] = accept
+	}
+
+	for tag, contentType := range tags {
+		if contentType == ContentTypeCSV {
+			continue
+		}
+
+		//? the client has another representation than the one it asks for now
+		response := serveRequest(router, http.MethodGet, "/holidays/2", "", map[string]string{"Accept": ContentTypeCSV, "If-None-Match": tag})
+		if response.Code != http.StatusOK {
+			t.Errorf("CSV with the %s ETag in If-None-Match answered %d", contentType, response.Code)
+		}
+	}
+}
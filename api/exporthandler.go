@@ -0,0 +1,228 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"travelagency/repository"
+)
+
+const (
+	exportFormatCSV    = "csv"
+	exportFormatNDJSON = "ndjson"
+
+	//? rows are flushed in batches so large exports reach the client while they are read
+	exportFlushRows = 100
+)
+
+// exportWriter writes the rows of an export as CSV or NDJSON and flushes them while streaming.
+type exportWriter struct {
+	writer    http.ResponseWriter
+	csv       *csv.Writer
+	json      *json.Encoder
+	unflushed int
+}
+
+func newExportWriter(writer http.ResponseWriter, format string, name string, columns []string) *exportWriter {
+	export := exportWriter{writer: writer}
+
+	if format == exportFormatCSV {
+		writer.Header().Set("Content-Type", ContentTypeCSV+"; charset=utf-8")
+		writer.Header().Set("Content-Disposition", `attachment; filename="`+name+`.csv"`)
+		export.csv = csv.NewWriter(writer)
+		export.csv.Write(columns)
+	} else {
+		writer.Header().Set("Content-Type", ContentTypeNDJSON)
+		writer.Header().Set("Content-Disposition", `attachment; filename="`+name+`.ndjson"`)
+		export.json = json.NewEncoder(writer)
+	}
+
+	writer.WriteHeader(http.StatusOK)
+	return &export
+}
+
+// write writes a row, body is used for NDJSON and record for CSV.
+func (export *exportWriter) write(body any, record []string) error {
+	var err error
+	if export.csv != nil {
+		err = export.csv.Write(record)
+	} else {
+		err = export.json.Encode(body)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	export.unflushed++
+	if export.unflushed >= exportFlushRows {
+		export.flush()
+	}
+
+	return nil
+}
+
+func (export *exportWriter) flush() {
+	if export.csv != nil {
+		export.csv.Flush()
+	}
+
+	if flusher, ok := export.writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	export.unflushed = 0
+}
+
+// parseExportFormat reads `format=csv|ndjson`, NDJSON is the default.
+func parseExportFormat(request *http.Request) (string, *APIResponse) {
+	switch format := request.URL.Query().Get("format"); format {
+	case "", exportFormatNDJSON:
+		return exportFormatNDJSON, nil
+	case exportFormatCSV:
+		return exportFormatCSV, nil
+	default:
+		response := BadRequestError([]byte("format must be " + exportFormatCSV + " or " + exportFormatNDJSON))
+		return "", &response
+	}
+}
+
+// RespondExportLocations streams every location in the format that `/import/locations` accepts.
+func RespondExportLocations(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		writeResponse(writer, InternalServerError([]byte("not implemented\n")))
+		return
+	}
+
+	format, response := parseExportFormat(request)
+	if response != nil {
+		writeResponse(writer, *response)
+		return
+	}
+
+	includeDeleted, response := parseIncludeDeleted(request)
+	if response != nil {
+		writeResponse(writer, *response)
+		return
+	}
+
+	locationsRepo, err := repository.NewLocationsRepo(nil)
+	if err != nil {
+		writeResponse(writer, InternalServerError([]byte("couldn't connect to database")))
+		return
+	}
+
+	locationsRepo.SetContext(request.Context())
+
+	//? the status is sent with the first row, later errors can only end the stream early
+	export := newExportWriter(writer, format, "locations", locationImportColumns)
+	locationsRepo.Each(includeDeleted, func(entity repository.LocationsEntity) error {
+		body := locationHandlerPutBody{
+			ID:        entity.ID,
+			City:      entity.City,
+			Country:   entity.Country,
+			Number:    entity.Number,
+			Street:    entity.Street,
+			ImageUrl:  entity.ImageUrl,
+			Latitude:  entity.Latitude,
+			Longitude: entity.Longitude,
+		}
+
+		return export.write(body, []string{
+			strconv.FormatInt(entity.ID, 10),
+			entity.Street,
+			entity.Number,
+			entity.City,
+			entity.Country,
+			entity.ImageUrl,
+			formatOptionalFloat(entity.Latitude),
+			formatOptionalFloat(entity.Longitude),
+		})
+	})
+	export.flush()
+}
+
+// RespondExportHolidays streams the holidays matching the `/holidays` filters in the format that `/import/holidays` accepts.
+func RespondExportHolidays(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		writeResponse(writer, InternalServerError([]byte("not implemented\n")))
+		return
+	}
+
+	format, response := parseExportFormat(request)
+	if response != nil {
+		writeResponse(writer, *response)
+		return
+	}
+
+	filter, err := parseHolidaysFilter(request)
+	if err != nil {
+		writeResponse(writer, BadRequestError([]byte(err.Error())))
+		return
+	}
+
+	includeDeleted, response := parseIncludeDeleted(request)
+	if response != nil {
+		writeResponse(writer, *response)
+		return
+	}
+
+	filter.IncludeDeleted = includeDeleted
+
+	holidaysRepo, err := repository.NewHolidaysRepo(nil)
+	if err != nil {
+		writeResponse(writer, InternalServerError([]byte("couldn't connect to database")))
+		return
+	}
+
+	holidaysRepo.SetContext(request.Context())
+
+	//? the status is sent with the first row, later errors can only end the stream early
+	export := newExportWriter(writer, format, "holidays", holidayImportColumns)
+	holidaysRepo.Each(filter, func(entity repository.HolidaysEntity) error {
+		body := holidayHandlerPutBody{
+			ID:        entity.ID,
+			Location:  entity.LocationId,
+			Title:     entity.Title,
+			StartDate: entity.StartDate,
+			Duration:  entity.Duration,
+			Price:     entity.Price,
+			FreeSlots: entity.FreeSlots,
+			Category:  entity.CategoryId,
+			Tags:      entity.TagIds,
+		}
+
+		category := ""
+		if entity.CategoryId != nil {
+			category = strconv.FormatInt(*entity.CategoryId, 10)
+		}
+
+		tags := make([]string, 0, len(entity.TagIds))
+		for _, tag := range entity.TagIds {
+			tags = append(tags, strconv.FormatInt(tag, 10))
+		}
+
+		return export.write(body, []string{
+			strconv.FormatInt(entity.ID, 10),
+			entity.Title,
+			entity.StartDate,
+			strconv.Itoa(entity.Duration),
+			strconv.FormatFloat(entity.Price, 'f', -1, 64),
+			strconv.Itoa(entity.FreeSlots),
+			strconv.FormatInt(entity.LocationId, 10),
+			category,
+			strings.Join(tags, ";"),
+		})
+	})
+	export.flush()
+}
+
+func formatOptionalFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+	"travelagency/repository"
+)
+
+// Version is the release of the server, set when building with
+// `-ldflags "-X travelagency/api.Version=1.2.3"`.
+var Version = "dev"
+
+// MinFreeDiskBytes is the free space on the disk of the database below which the server is not ready.
+var MinFreeDiskBytes uint64 = 100 << 20
+
+const (
+	healthCheckTimeout = 2 * time.Second
+
+	healthStatusUp      = "up"
+	healthStatusDown    = "down"
+	healthStatusSkipped = "skipped"
+)
+
+type healthHandler struct{}
+
+type healthCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type buildInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` //? built from a working tree with changes
+	GoVersion string `json:"goVersion"`
+}
+
+type healthStatus struct {
+	Status string        `json:"status"`
+	Checks []healthCheck `json:"checks,omitempty"`
+	Build  buildInfo     `json:"build"`
+}
+
+// RespondLivez answers as long as the server can handle requests, it checks nothing else so that a
+// database outage does not get the process restarted.
+func RespondLivez(writer http.ResponseWriter, request *http.Request) {
+	handler := healthHandler{}
+	writeResponse(writer, handler.respond(request, handler.live))
+}
+
+// RespondReadyz answers 503 while the database, its schema or its disk is not usable.
+func RespondReadyz(writer http.ResponseWriter, request *http.Request) {
+	handler := healthHandler{}
+	writeResponse(writer, handler.respond(request, handler.ready))
+}
+
+// RespondHealth is the readiness check under its old path.
+func RespondHealth(writer http.ResponseWriter, request *http.Request) {
+	handler := healthHandler{}
+	writeResponse(writer, deprecated(handler.respond(request, handler.ready), "/readyz"))
+}
+
+func (h *healthHandler) respond(request *http.Request, check func(request *http.Request) healthStatus) APIResponse {
+	switch request.Method {
+
+	case http.MethodGet:
+		return h.handleGet(check(request))
+	default:
+		return InternalServerError([]byte("not implemented\n"))
+	}
+}
+
+func (h *healthHandler) handleGet(status healthStatus) APIResponse {
+	statusCode := http.StatusOK
+	if status.Status != healthStatusUp {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	jsonBody, _ := json.Marshal(status)
+	return APIResponse{
+		Status:      statusCode,
+		Content:     jsonBody,
+		ContentType: &ContentTypeJSON,
+		Header:      http.Header{"Cache-Control": []string{"no-store"}},
+	}
+}
+
+func (h *healthHandler) live(request *http.Request) healthStatus {
+	return healthStatus{
+		Status: healthStatusUp,
+		Build:  currentBuild(),
+	}
+}
+
+func (h *healthHandler) ready(request *http.Request) healthStatus {
+	ctx, cancel := context.WithTimeout(request.Context(), healthCheckTimeout)
+	defer cancel()
+
+	checks := []healthCheck{
+		checkDatabase(ctx),
+		checkSchema(ctx),
+		checkDisk(),
+	}
+
+	return healthStatus{
+		Status: overallStatus(checks),
+		Checks: checks,
+		Build:  currentBuild(),
+	}
+}
+
+// overallStatus is down when one of the checks is down, skipped checks do not count.
+func overallStatus(checks []healthCheck) string {
+	for _, check := range checks {
+		if check.Status == healthStatusDown {
+			return healthStatusDown
+		}
+	}
+
+	return healthStatusUp
+}
+
+func checkDatabase(ctx context.Context) healthCheck {
+	if err := repository.PingDB(ctx); err != nil {
+		return healthCheck{Name: "database", Status: healthStatusDown, Error: err.Error()}
+	}
+
+	return healthCheck{Name: "database", Status: healthStatusUp}
+}
+
+func checkSchema(ctx context.Context) healthCheck {
+	version, err := repository.CheckSchemaVersion(ctx)
+	if err != nil {
+		return healthCheck{Name: "schema", Status: healthStatusDown, Error: err.Error()}
+	}
+
+	return healthCheck{Name: "schema", Status: healthStatusUp, Detail: fmt.Sprintf("version %d", version)}
+}
+
+func checkDisk() healthCheck {
+	free, err := repository.FreeDiskBytes()
+	if errors.Is(err, repository.ErrDiskSpaceUnsupported) {
+		return healthCheck{Name: "disk", Status: healthStatusSkipped, Detail: err.Error()}
+	}
+
+	if err != nil {
+		return healthCheck{Name: "disk", Status: healthStatusDown, Error: err.Error()}
+	}
+
+	detail := fmt.Sprintf("%d MB free", free>>20)
+	if free < MinFreeDiskBytes {
+		return healthCheck{Name: "disk", Status: healthStatusDown, Detail: detail, Error: fmt.Sprintf("less than %d MB free", MinFreeDiskBytes>>20)}
+	}
+
+	return healthCheck{Name: "disk", Status: healthStatusUp, Detail: detail}
+}
+
+// currentBuild reads the version control details Go embeds in the binary.
+func currentBuild() buildInfo {
+	build := buildInfo{Version: Version}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return build
+	}
+
+	build.GoVersion = info.GoVersion
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			build.Revision = setting.Value
+		case "vcs.time":
+			build.Time = setting.Value
+		case "vcs.modified":
+			build.Modified = setting.Value == "true"
+		}
+	}
+
+	return build
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"errors"
+	"testing"
+	"travelagency/repository"
+)
+
+func TestSkippedChecksKeepTheServerReady(t *testing.T) {
+	checks := []healthCheck{
+		{Name: "database", Status: healthStatusUp},
+		{Name: "disk", Status: healthStatusSkipped},
+	}
+	if status := overallStatus(checks); status != healthStatusUp {
+		t.Errorf("a skipped check made the server %s", status)
+	}
+
+	checks = append(checks, healthCheck{Name: "schema", Status: healthStatusDown})
+	if status := overallStatus(checks); status != healthStatusDown {
+		t.Errorf("a failed check left the server %s", status)
+	}
+
+	//? on unix the disk is checked, elsewhere it is skipped instead of failing
+	disk := checkDisk()
+	if _, err := repository.FreeDiskBytes(); errors.Is(err, repository.ErrDiskSpaceUnsupported) != (disk.Status == healthStatusSkipped) {
+		t.Errorf("the disk check is %s with the error %v", disk.Status, err)
+	}
+}
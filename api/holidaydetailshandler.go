@@ -1,6 +1,7 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"strconv"
@@ -23,17 +24,15 @@ func RespondHolidayDetails(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
+	actor := actorName(request)
+	holidaysRepo.SetActor(actor)
+	holidaysRepo.SetContext(request.Context())
+
 	handler := holidayDetailsHandler{
 		holidayRepo: holidaysRepo,
 	}
 
-	response := handler.respond(request)
-	if response.ContentType != nil {
-		writer.Header().Set("Content-Type", *response.ContentType)
-	}
-
-	writer.WriteHeader(response.Status)
-	writer.Write(response.Content)
+	writeResponse(writer, handler.respond(request))
 }
 
 func (h *holidayDetailsHandler) respond(request *http.Request) APIResponse {
@@ -41,6 +40,10 @@ func (h *holidayDetailsHandler) respond(request *http.Request) APIResponse {
 
 	case http.MethodGet:
 		return h.handleGet(request)
+	case http.MethodPut:
+		return h.handlePut(request)
+	case http.MethodPatch:
+		return h.handlePatch(request)
 	case http.MethodDelete:
 		return h.handleDelete(request)
 	default:
@@ -60,17 +63,125 @@ func (h *holidayDetailsHandler) handleGet(request *http.Request) APIResponse {
 		return InternalServerError([]byte(err.Error()))
 	}
 
+	includeDeleted, response := parseIncludeDeleted(request)
+	if response != nil {
+		return *response
+	}
+
 	entity, err := h.holidayRepo.GetByID(id)
+	if err == sql.ErrNoRows {
+		return DefaultNotFoundError()
+	}
+
 	if err != nil {
 		return InternalServerError([]byte(err.Error()))
 	}
 
-	if entity == nil {
+	if entity == nil || (entity.DeletedAt != nil && !includeDeleted) {
+		return DefaultNotFoundError()
+	}
+
+	jsonBody, _ := json.Marshal(entity)
+	return entityResponse(request, jsonBody, entity.Version)
+}
+
+func (h *holidayDetailsHandler) handlePut(request *http.Request) APIResponse {
+	vars := mux.Vars(request)
+	idStr, exists := vars["id"]
+	if !exists {
+		return BadRequestError([]byte("id is empty"))
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return BadRequestError([]byte(err.Error()))
+	}
+
+	var body holidayHandlerPutBody
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		return decodeErrorResponse(err)
+	}
+
+	//? the id can be left out of the body but must not contradict the path
+	if body.ID != 0 && body.ID != id {
+		return BadRequestError([]byte("id in the body does not match the path"))
+	}
+
+	body.ID = id
+	return putHoliday(h.holidayRepo, request, body)
+}
+
+func (h *holidayDetailsHandler) handlePatch(request *http.Request) APIResponse {
+	vars := mux.Vars(request)
+	idStr, exists := vars["id"]
+	if !exists {
+		return BadRequestError([]byte("id is empty"))
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return BadRequestError([]byte(err.Error()))
+	}
+
+	current, err := h.holidayRepo.GetByID(id)
+	if err == sql.ErrNoRows || (err == nil && current.DeletedAt != nil) {
 		return DefaultNotFoundError()
 	}
 
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	if response := checkIfMatch(request, current.Version); response != nil {
+		return *response
+	}
+
+	//? the patch is applied to the same document that PUT accepts
+	document := holidayHandlerPutBody{
+		ID:        current.ID,
+		Location:  current.LocationId,
+		Title:     current.Title,
+		StartDate: current.StartDate,
+		Duration:  current.Duration,
+		Price:     current.Price,
+		FreeSlots: current.FreeSlots,
+		Category:  current.CategoryId,
+		Tags:      current.TagIds,
+	}
+
+	var body holidayHandlerPutBody
+	if response := applyPatch(request, document, &body); response != nil {
+		return *response
+	}
+
+	if body.ID != current.ID {
+		return BadRequestError([]byte("holiday id can not be changed"))
+	}
+
+	holiday := repository.HolidaysEntity{
+		ID:         current.ID,
+		Title:      body.Title,
+		StartDate:  body.StartDate,
+		Duration:   body.Duration,
+		Price:      body.Price,
+		FreeSlots:  body.FreeSlots,
+		LocationId: body.Location,
+		CategoryId: body.Category,
+		TagIds:     body.Tags,
+		Version:    current.Version,
+	}
+
+	if err := validateHoliday(holiday); err != nil {
+		return BadRequestError([]byte(err.Error()))
+	}
+
+	entity, err := h.holidayRepo.Update(holiday)
+	if err != nil {
+		return repositoryErrorResponse(err)
+	}
+
 	jsonBody, _ := json.Marshal(entity)
-	return OKContentType(jsonBody, ContentTypeJSON)
+	return entityResponse(request, jsonBody, entity.Version)
 }
 
 func (h *holidayDetailsHandler) handleDelete(request *http.Request) APIResponse {
@@ -85,10 +196,23 @@ func (h *holidayDetailsHandler) handleDelete(request *http.Request) APIResponse
 		return InternalServerError([]byte(err.Error()))
 	}
 
-	err = h.holidayRepo.Delete(id)
+	entity, err := h.holidayRepo.GetByID(id)
+	if err == sql.ErrNoRows || (err == nil && entity.DeletedAt != nil) {
+		return DefaultNotFoundError()
+	}
+
 	if err != nil {
 		return InternalServerError([]byte(err.Error()))
 	}
 
+	if response := checkIfMatch(request, entity.Version); response != nil {
+		return *response
+	}
+
+	err = h.holidayRepo.Delete(id, entity.Version)
+	if err != nil {
+		return repositoryErrorResponse(err)
+	}
+
 	return OKContentType([]byte("true"), ContentTypeJSON)
 }
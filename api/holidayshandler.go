@@ -1,6 +1,7 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"strconv"
@@ -12,12 +13,14 @@ type holidaysHandler struct {
 }
 
 type holidayHandlerPostBody struct {
-	Location  int64  `json:"location"`
-	Title     string `json:"title"`
-	StartDate string `json:"startDate"`
-	Duration  int    `json:"duration"`
-	Price     string `json:"price"`
-	FreeSlots int    `json:"freeSlots"`
+	Location  int64   `json:"location"`
+	Title     string  `json:"title"`
+	StartDate string  `json:"startDate"`
+	Duration  int     `json:"duration"`
+	Price     string  `json:"price"`
+	FreeSlots int     `json:"freeSlots"`
+	Category  *int64  `json:"category"`
+	Tags      []int64 `json:"tags"`
 }
 
 type holidayHandlerPutBody struct {
@@ -28,6 +31,13 @@ type holidayHandlerPutBody struct {
 	Duration  int     `json:"duration"`
 	Price     float64 `json:"price"`
 	FreeSlots int     `json:"freeSlots"`
+	Category  *int64  `json:"category"`
+	Tags      []int64 `json:"tags"`
+}
+
+type holidaysHandlerFacetedResponse struct {
+	Holidays []repository.HolidaysEntity `json:"holidays"`
+	Facets   repository.HolidayFacets    `json:"facets"`
 }
 
 func RespondHolidays(writer http.ResponseWriter, request *http.Request) {
@@ -40,17 +50,15 @@ func RespondHolidays(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
+	actor := actorName(request)
+	holidaysRepo.SetActor(actor)
+	holidaysRepo.SetContext(request.Context())
+
 	handler := holidaysHandler{
 		holidaysRepo: holidaysRepo,
 	}
 
-	response := handler.respond(request)
-	if response.ContentType != nil {
-		writer.Header().Set("Content-Type", *response.ContentType)
-	}
-
-	writer.WriteHeader(response.Status)
-	writer.Write(response.Content)
+	writeResponse(writer, handler.respond(request))
 }
 
 func (h *holidaysHandler) respond(request *http.Request) APIResponse {
@@ -71,7 +79,7 @@ func (h *holidaysHandler) handlePost(request *http.Request) APIResponse {
 	var body holidayHandlerPostBody
 	err := json.NewDecoder(request.Body).Decode(&body)
 	if err != nil {
-		return InternalServerError([]byte(err.Error()))
+		return decodeErrorResponse(err)
 	}
 
 	parsedPrice, err := strconv.ParseFloat(body.Price, 64)
@@ -79,35 +87,62 @@ func (h *holidaysHandler) handlePost(request *http.Request) APIResponse {
 		return InternalServerError([]byte(err.Error()))
 	}
 
-	entity, err := h.holidaysRepo.Insert(repository.HolidaysEntity{
+	holiday := repository.HolidaysEntity{
 		Title:      body.Title,
 		StartDate:  body.StartDate,
 		Duration:   body.Duration,
 		Price:      parsedPrice,
 		FreeSlots:  body.FreeSlots,
 		LocationId: body.Location,
-	})
+		CategoryId: body.Category,
+		TagIds:     body.Tags,
+	}
+
+	if err := validateHoliday(holiday); err != nil {
+		return BadRequestError([]byte(err.Error()))
+	}
 
+	entity, err := h.holidaysRepo.Insert(holiday)
 	if err != nil {
-		return InternalServerError([]byte(err.Error()))
+		return repositoryErrorResponse(err)
 	}
 
 	jsonBody, _ := json.Marshal(entity)
-	return OKContentType(jsonBody, ContentTypeJSON)
+	return Created(jsonBody, ContentTypeJSON, "/holidays/"+strconv.FormatInt(entity.ID, 10)).WithHeader("ETag", etag(entity.Version, ContentTypeJSON, jsonBody))
 }
 
+// handlePut updates the holiday with the id in the body, it is kept for old clients and replaced by PUT /holidays/{id}.
 func (h *holidaysHandler) handlePut(request *http.Request) APIResponse {
 	var body holidayHandlerPutBody
 	err := json.NewDecoder(request.Body).Decode(&body)
 	if err != nil {
-		return InternalServerError([]byte(err.Error()))
+		return decodeErrorResponse(err)
 	}
 
 	if body.ID == 0 {
 		return BadRequestError([]byte("invalid holiday id"))
 	}
 
-	entity, err := h.holidaysRepo.Update(repository.HolidaysEntity{
+	response := putHoliday(h.holidaysRepo, request, body)
+	return deprecated(response, "/holidays/"+strconv.FormatInt(body.ID, 10))
+}
+
+// putHoliday replaces every field of the holiday with the body.
+func putHoliday(holidaysRepo *repository.HolidaysRepo, request *http.Request, body holidayHandlerPutBody) APIResponse {
+	current, err := holidaysRepo.GetByID(body.ID)
+	if err == sql.ErrNoRows || (err == nil && current.DeletedAt != nil) {
+		return DefaultNotFoundError()
+	}
+
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	if response := checkIfMatch(request, current.Version); response != nil {
+		return *response
+	}
+
+	holiday := repository.HolidaysEntity{
 		ID:         body.ID,
 		Title:      body.Title,
 		StartDate:  body.StartDate,
@@ -115,26 +150,50 @@ func (h *holidaysHandler) handlePut(request *http.Request) APIResponse {
 		Price:      body.Price,
 		FreeSlots:  body.FreeSlots,
 		LocationId: body.Location,
-	})
+		CategoryId: body.Category,
+		TagIds:     body.Tags,
+		Version:    current.Version,
+	}
 
+	if err := validateHoliday(holiday); err != nil {
+		return BadRequestError([]byte(err.Error()))
+	}
+
+	entity, err := holidaysRepo.Update(holiday)
 	if err != nil {
-		return InternalServerError([]byte(err.Error()))
+		return repositoryErrorResponse(err)
 	}
 
 	jsonBody, _ := json.Marshal(entity)
-	return OKContentType(jsonBody, ContentTypeJSON)
+	return entityResponse(request, jsonBody, entity.Version)
 }
 
 func (h *holidaysHandler) handleGet(request *http.Request) APIResponse {
-	locationFilter := request.URL.Query().Get("location")
-	startDateFilter := request.URL.Query().Get("startDate")
-	durationFilter := request.URL.Query().Get("duration")
+	filter, err := parseHolidaysFilter(request)
+	if err != nil {
+		return BadRequestError([]byte(err.Error()))
+	}
 
-	data, err := h.holidaysRepo.GetAll(locationFilter, startDateFilter, durationFilter)
+	includeDeleted, response := parseIncludeDeleted(request)
+	if response != nil {
+		return *response
+	}
+
+	filter.IncludeDeleted = includeDeleted
+	data, err := h.holidaysRepo.GetAll(filter)
 	if err != nil {
 		return InternalServerError([]byte(err.Error()))
 	}
 
+	//? facets change the shape of the response so they are only returned on request
+	if request.URL.Query().Get("facets") == "true" {
+		jsonBody, _ := json.Marshal(holidaysHandlerFacetedResponse{
+			Holidays: data,
+			Facets:   repository.Facets(data),
+		})
+		return OKNegotiated(request, jsonBody)
+	}
+
 	jsonBody, _ := json.Marshal(data)
-	return OKContentType(jsonBody, ContentTypeJSON)
+	return OKNegotiated(request, jsonBody)
 }
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+	"travelagency/repository"
+)
+
+// IdempotencyKeyTTL is how long the responses to requests with an `Idempotency-Key` are kept for retries.
+// It is set from the `idempotency.ttl` configuration.
+var IdempotencyKeyTTL = 24 * time.Hour
+
+const maxIdempotencyKeyLength = 255
+
+// replayedHeaders are the headers of the handlers kept with the response, the ones set by the other
+// middleware, like X-Request-ID and RateLimit-*, belong to the retry.
+var replayedHeaders = []string{"Content-Type", "Location", "ETag"}
+
+// responseRecorder passes the response through while keeping a copy to store it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (recorder *responseRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *responseRecorder) Write(content []byte) (int, error) {
+	recorder.body.Write(content)
+	return recorder.ResponseWriter.Write(content)
+}
+
+// requestHash identifies a request by its method, target and body.
+func requestHash(request *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, request.Method+" "+request.URL.RequestURI()+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Idempotency stores the response of a POST with an `Idempotency-Key` header per caller and key
+// and replays it when the request is retried. Callers are the API keys, or the client IPs of
+// requests without one. Reusing a key for another request is rejected with 422.
+func Idempotency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		key := request.Header.Get("Idempotency-Key")
+		if request.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			writeResponse(writer, BadRequestError([]byte("Idempotency-Key is too long")))
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(nil, request.Body, maxUploadBytes))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				writeResponse(writer, PayloadTooLargeError([]byte(err.Error())))
+				return
+			}
+
+			writeResponse(writer, BadRequestError([]byte(err.Error())))
+			return
+		}
+		request.Body = io.NopCloser(bytes.NewReader(body))
+
+		idempotencyRepo, err := repository.NewIdempotencyRepo(nil)
+		if err != nil {
+			writeResponse(writer, InternalServerError([]byte("couldn't connect to database")))
+			return
+		}
+
+		idempotencyRepo.SetContext(request.Context())
+
+		caller := clientKey(request)
+		hash := requestHash(request, body)
+
+		stored, err := idempotencyRepo.Reserve(caller, key, hash, IdempotencyKeyTTL)
+		switch {
+		case err != nil:
+			writeResponse(writer, InternalServerError([]byte(err.Error())))
+			return
+		case stored != nil && stored.RequestHash != hash:
+			writeResponse(writer, UnprocessableEntityError([]byte("Idempotency-Key was already used for a different request")))
+			return
+		case stored != nil && !stored.Completed():
+			writeResponse(writer, ConflictError([]byte("a request with this Idempotency-Key is still being handled")))
+			return
+		case stored != nil:
+			replay := APIResponse{
+				Status:  stored.Status,
+				Content: stored.Content,
+				Header:  stored.Header,
+			}
+
+			writeResponse(writer, replay.WithHeader("Idempotent-Replayed", "true"))
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: writer, status: http.StatusOK}
+		completed := false
+		defer func() {
+			//? failed or interrupted requests free the key so that the client can retry them
+			if !completed {
+				idempotencyRepo.Release(caller, key)
+			}
+		}()
+
+		next.ServeHTTP(recorder, request)
+
+		if recorder.status < http.StatusInternalServerError {
+			header := http.Header{}
+			for _, name := range replayedHeaders {
+				if values := recorder.Header().Values(name); len(values) > 0 {
+					header[name] = values
+				}
+			}
+
+			completed = idempotencyRepo.Complete(caller, key, recorder.status, header, recorder.body.Bytes()) == nil
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestIdempotencyScopesKeysAndReplaysTheHandlerHeaders(t *testing.T) {
+	useTestDB(t)
+
+	router := mux.NewRouter()
+	router.Use(RequestID, Idempotency)
+	RegisterRoutes(router, allFeatures)
+
+	post := func(remoteAddr string, requestID string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/categories", strings.NewReader(`{"name": "Cruises"}`))
+		request.RemoteAddr = remoteAddr
+		request.Header.Set("Content-Type", ContentTypeJSON)
+		request.Header.Set("Idempotency-Key", "create-cruises")
+		request.Header.Set(requestIDHeader, requestID)
+
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		return response
+	}
+
+	first := post("192.0.2.1:50000", "first")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("the first request answered %d: %s", first.Code, first.Body.String())
+	}
+
+	retry := post("192.0.2.1:50001", "retry")
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("the retry of the same client was not replayed: %d %s", retry.Code, retry.Body.String())
+	}
+
+	if retry.Body.String() != first.Body.String() || retry.Header().Get("Location") != first.Header().Get("Location") {
+		t.Errorf("the replay differs from the stored response")
+	}
+
+	//? headers of the outer middleware belong to the retry
+	if got := retry.Header().Get(requestIDHeader); got != "retry" {
+		t.Errorf("the replay has the request id %q, not the one of the retry", got)
+	}
+
+	//? a client without an API key must not get the response stored for another one
+	other := post("198.51.100.7:50000", "other")
+	if other.Header().Get("Idempotent-Replayed") != "" || other.Body.String() == first.Body.String() {
+		t.Errorf("another anonymous client got the stored response")
+	}
+}
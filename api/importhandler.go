@@ -0,0 +1,442 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"travelagency/repository"
+)
+
+const (
+	ContentTypeCSV    = "text/csv"
+	ContentTypeNDJSON = "application/x-ndjson"
+
+	importModeAtomic     = "atomic"
+	importModeBestEffort = "bestEffort"
+
+	maxImportBytes = 32 << 20
+)
+
+var (
+	locationImportColumns = []string{"id", "street", "number", "city", "country", "imageUrl", "latitude", "longitude"}
+	holidayImportColumns  = []string{"id", "title", "startDate", "duration", "price", "freeSlots", "location", "category", "tags"}
+)
+
+// importLine is a row of an import with the line it starts on, err is set when it could not be decoded.
+type importLine[T any] struct {
+	line int
+	body T
+	err  error
+}
+
+type importHandler struct {
+	locationsRepo *repository.LocationsRepo
+	holidaysRepo  *repository.HolidaysRepo
+}
+
+func RespondImportLocations(writer http.ResponseWriter, request *http.Request) {
+	locationsRepo, err := repository.NewLocationsRepo(nil)
+	if err != nil {
+		response := InternalServerError([]byte("couldn't connect to database"))
+
+		writer.WriteHeader(response.Status)
+		writer.Write(response.Content)
+		return
+	}
+
+	locationsRepo.SetActor(actorName(request))
+	locationsRepo.SetContext(request.Context())
+
+	handler := importHandler{
+		locationsRepo: locationsRepo,
+	}
+
+	writeResponse(writer, handler.respond(request, handler.importLocations))
+}
+
+func RespondImportHolidays(writer http.ResponseWriter, request *http.Request) {
+	holidaysRepo, err := repository.NewHolidaysRepo(nil)
+	if err != nil {
+		response := InternalServerError([]byte("couldn't connect to database"))
+
+		writer.WriteHeader(response.Status)
+		writer.Write(response.Content)
+		return
+	}
+
+	holidaysRepo.SetActor(actorName(request))
+	holidaysRepo.SetContext(request.Context())
+
+	handler := importHandler{
+		holidaysRepo: holidaysRepo,
+	}
+
+	writeResponse(writer, handler.respond(request, handler.importHolidays))
+}
+
+func (h *importHandler) respond(request *http.Request, handlePost func(request *http.Request, options repository.ImportOptions) APIResponse) APIResponse {
+	switch request.Method {
+
+	case http.MethodPost:
+		options, response := parseImportOptions(request)
+		if response != nil {
+			return *response
+		}
+
+		request.Body = http.MaxBytesReader(nil, request.Body, maxImportBytes)
+		return handlePost(request, options)
+	default:
+		return InternalServerError([]byte("not implemented\n"))
+	}
+}
+
+// parseImportOptions reads `dryRun=true` and `mode=atomic|bestEffort` ( atomic by default ).
+func parseImportOptions(request *http.Request) (repository.ImportOptions, *APIResponse) {
+	query := request.URL.Query()
+	options := repository.ImportOptions{}
+
+	if value := query.Get("dryRun"); value != "" {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			response := BadRequestError([]byte("dryRun must be true or false"))
+			return options, &response
+		}
+
+		options.DryRun = dryRun
+	}
+
+	switch query.Get("mode") {
+	case "", importModeAtomic:
+	case importModeBestEffort:
+		options.BestEffort = true
+	default:
+		response := BadRequestError([]byte("mode must be " + importModeAtomic + " or " + importModeBestEffort))
+		return options, &response
+	}
+
+	return options, nil
+}
+
+func (h *importHandler) importLocations(request *http.Request, options repository.ImportOptions) APIResponse {
+	lines, response := readImport(request, locationImportColumns, locationFromRecord)
+	if response != nil {
+		return *response
+	}
+
+	rows := []repository.ImportRow[repository.LocationsEntity]{}
+	invalid := []repository.ImportError{}
+	for _, line := range lines {
+		if line.err != nil {
+			invalid = append(invalid, repository.ImportError{Line: line.line, Error: line.err.Error()})
+			continue
+		}
+
+		//? ids of exported rows are ignored, every row creates a new location
+		entity := repository.LocationsEntity{
+			City:      line.body.City,
+			Country:   line.body.Country,
+			Number:    line.body.Number,
+			Street:    line.body.Street,
+			ImageUrl:  line.body.ImageUrl,
+			Latitude:  line.body.Latitude,
+			Longitude: line.body.Longitude,
+		}
+
+		if err := validateLocation(entity); err != nil {
+			invalid = append(invalid, repository.ImportError{Line: line.line, Error: err.Error()})
+			continue
+		}
+
+		rows = append(rows, repository.ImportRow[repository.LocationsEntity]{Line: line.line, Entity: entity})
+	}
+
+	result, err := h.locationsRepo.Import(rows, importOptionsFor(options, invalid))
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	return importResponse(result, invalid, options)
+}
+
+func (h *importHandler) importHolidays(request *http.Request, options repository.ImportOptions) APIResponse {
+	lines, response := readImport(request, holidayImportColumns, holidayFromRecord)
+	if response != nil {
+		return *response
+	}
+
+	rows := []repository.ImportRow[repository.HolidaysEntity]{}
+	invalid := []repository.ImportError{}
+	for _, line := range lines {
+		if line.err != nil {
+			invalid = append(invalid, repository.ImportError{Line: line.line, Error: line.err.Error()})
+			continue
+		}
+
+		//? ids of exported rows are ignored, every row creates a new holiday
+		entity := repository.HolidaysEntity{
+			Title:      line.body.Title,
+			StartDate:  line.body.StartDate,
+			Duration:   line.body.Duration,
+			Price:      line.body.Price,
+			FreeSlots:  line.body.FreeSlots,
+			LocationId: line.body.Location,
+			CategoryId: line.body.Category,
+			TagIds:     line.body.Tags,
+		}
+
+		if err := validateHoliday(entity); err != nil {
+			invalid = append(invalid, repository.ImportError{Line: line.line, Error: err.Error()})
+			continue
+		}
+
+		rows = append(rows, repository.ImportRow[repository.HolidaysEntity]{Line: line.line, Entity: entity})
+	}
+
+	result, err := h.holidaysRepo.Import(rows, importOptionsFor(options, invalid))
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	return importResponse(result, invalid, options)
+}
+
+// importOptionsFor turns an atomic import with invalid rows into a dry run so the remaining rows are still checked.
+func importOptionsFor(options repository.ImportOptions, invalid []repository.ImportError) repository.ImportOptions {
+	if len(invalid) > 0 && !options.BestEffort {
+		options.DryRun = true
+	}
+
+	return options
+}
+
+// importResponse merges the rows rejected before and by the repository, a failed atomic import answers 422.
+func importResponse(result *repository.ImportResult, invalid []repository.ImportError, options repository.ImportOptions) APIResponse {
+	result.DryRun = options.DryRun
+	result.Errors = mergeImportErrors(invalid, result.Errors)
+
+	jsonBody, _ := json.Marshal(result)
+	if len(result.Errors) > 0 && !options.BestEffort {
+		return APIResponse{
+			Status:      http.StatusUnprocessableEntity,
+			Content:     jsonBody,
+			ContentType: &ContentTypeJSON,
+		}
+	}
+
+	return OKContentType(jsonBody, ContentTypeJSON)
+}
+
+// mergeImportErrors merges two lists of errors ordered by line.
+func mergeImportErrors(left []repository.ImportError, right []repository.ImportError) []repository.ImportError {
+	merged := make([]repository.ImportError, 0, len(left)+len(right))
+	for len(left) > 0 && len(right) > 0 {
+		if left[0].Line <= right[0].Line {
+			merged = append(merged, left[0])
+			left = left[1:]
+		} else {
+			merged = append(merged, right[0])
+			right = right[1:]
+		}
+	}
+
+	merged = append(merged, left...)
+	return append(merged, right...)
+}
+
+// readImport decodes the CSV or NDJSON body of the request. CSV files need a header with the given columns,
+// NDJSON lines are objects with the fields of the PUT body. Only errors of the whole file return a response.
+func readImport[T any](request *http.Request, columns []string, fromRecord func(record map[string]string) (T, error)) ([]importLine[T], *APIResponse) {
+	mediaType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+
+	var lines []importLine[T]
+	var err error
+	switch mediaType {
+	case ContentTypeCSV:
+		lines, err = readCSVImport(request.Body, columns, fromRecord)
+	case ContentTypeNDJSON, "application/jsonl":
+		lines, err = readNDJSONImport[T](request.Body)
+	default:
+		response := UnsupportedMediaTypeError([]byte("Content-Type must be " + ContentTypeCSV + " or " + ContentTypeNDJSON))
+		return nil, &response
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		response := PayloadTooLargeError([]byte(fmt.Sprintf("imports are limited to %d bytes", maxImportBytes)))
+		return nil, &response
+	}
+
+	if err != nil {
+		response := BadRequestError([]byte(err.Error()))
+		return nil, &response
+	}
+
+	if len(lines) == 0 {
+		response := BadRequestError([]byte("the import has no rows"))
+		return nil, &response
+	}
+
+	return lines, nil
+}
+
+func readCSVImport[T any](body io.Reader, columns []string, fromRecord func(record map[string]string) (T, error)) ([]importLine[T], error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, column := range header {
+		if !slices.Contains(columns, strings.TrimSpace(column)) {
+			return nil, fmt.Errorf("unknown column %q, the columns are %s", column, strings.Join(columns, ","))
+		}
+	}
+
+	lines := []importLine[T]{}
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return lines, nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && parseErr.Err == csv.ErrFieldCount {
+			err = nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		if len(fields) != len(header) {
+			lines = append(lines, importLine[T]{line: line, err: fmt.Errorf("expected %d fields but got %d", len(header), len(fields))})
+			continue
+		}
+
+		record := map[string]string{}
+		for i, column := range header {
+			record[strings.TrimSpace(column)] = strings.TrimSpace(fields[i])
+		}
+
+		body, err := fromRecord(record)
+		lines = append(lines, importLine[T]{line: line, body: body, err: err})
+	}
+}
+
+func readNDJSONImport[T any](body io.Reader) ([]importLine[T], error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportBytes)
+
+	lines := []importLine[T]{}
+	for line := 1; scanner.Scan(); line++ {
+		content := bytes.TrimSpace(scanner.Bytes())
+		if len(content) == 0 {
+			continue
+		}
+
+		var body T
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		err := decoder.Decode(&body)
+		lines = append(lines, importLine[T]{line: line, body: body, err: err})
+	}
+
+	return lines, scanner.Err()
+}
+
+func locationFromRecord(record map[string]string) (locationHandlerPutBody, error) {
+	body := locationHandlerPutBody{
+		Street:   record["street"],
+		Number:   record["number"],
+		City:     record["city"],
+		Country:  record["country"],
+		ImageUrl: record["imageUrl"],
+	}
+
+	var err error
+	if body.Latitude, err = parseOptionalFloat(record["latitude"], "latitude"); err != nil {
+		return body, err
+	}
+
+	body.Longitude, err = parseOptionalFloat(record["longitude"], "longitude")
+	return body, err
+}
+
+func holidayFromRecord(record map[string]string) (holidayHandlerPutBody, error) {
+	body := holidayHandlerPutBody{
+		Title:     record["title"],
+		StartDate: record["startDate"],
+		Tags:      []int64{},
+	}
+
+	var err error
+	if body.Duration, err = strconv.Atoi(record["duration"]); err != nil {
+		return body, errors.New("duration must be a number")
+	}
+
+	if body.Price, err = strconv.ParseFloat(record["price"], 64); err != nil {
+		return body, errors.New("price must be a number")
+	}
+
+	if body.FreeSlots, err = strconv.Atoi(record["freeSlots"]); err != nil {
+		return body, errors.New("freeSlots must be a number")
+	}
+
+	if body.Location, err = strconv.ParseInt(record["location"], 10, 64); err != nil {
+		return body, errors.New("location must be an id")
+	}
+
+	if record["category"] != "" {
+		category, err := strconv.ParseInt(record["category"], 10, 64)
+		if err != nil {
+			return body, errors.New("category must be an id")
+		}
+
+		body.Category = &category
+	}
+
+	//? tags are ids separated by semicolons
+	for _, tag := range strings.Split(record["tags"], ";") {
+		if tag = strings.TrimSpace(tag); tag == "" {
+			continue
+		}
+
+		id, err := strconv.ParseInt(tag, 10, 64)
+		if err != nil {
+			return body, errors.New("tags must be ids separated by ;")
+		}
+
+		body.Tags = append(body.Tags, id)
+	}
+
+	return body, nil
+}
+
+func parseOptionalFloat(value string, name string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, errors.New(name + " must be a number")
+	}
+
+	return &number, nil
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"strconv"
@@ -10,7 +11,8 @@ import (
 )
 
 type locationDetailsHandler struct {
-	locationsRepo *repository.LocationsRepo
+	locationsRepo      *repository.LocationsRepo
+	locationImagesRepo *repository.LocationImagesRepo
 }
 
 func RespondLocationDetails(writer http.ResponseWriter, request *http.Request) {
@@ -23,17 +25,27 @@ func RespondLocationDetails(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	handler := locationDetailsHandler{
-		locationsRepo: locationsRepo,
+	locationImagesRepo, err := repository.NewLocationImagesRepo(nil)
+	if err != nil {
+		response := InternalServerError([]byte("couldn't connect to database"))
+
+		writer.WriteHeader(response.Status)
+		writer.Write(response.Content)
+		return
 	}
 
-	response := handler.respond(request)
-	if response.ContentType != nil {
-		writer.Header().Set("Content-Type", *response.ContentType)
+	actor := actorName(request)
+	locationsRepo.SetActor(actor)
+	locationsRepo.SetContext(request.Context())
+	locationImagesRepo.SetActor(actor)
+	locationImagesRepo.SetContext(request.Context())
+
+	handler := locationDetailsHandler{
+		locationsRepo:      locationsRepo,
+		locationImagesRepo: locationImagesRepo,
 	}
 
-	writer.WriteHeader(response.Status)
-	writer.Write(response.Content)
+	writeResponse(writer, handler.respond(request))
 }
 
 func (h *locationDetailsHandler) respond(request *http.Request) APIResponse {
@@ -41,6 +53,10 @@ func (h *locationDetailsHandler) respond(request *http.Request) APIResponse {
 
 	case http.MethodGet:
 		return h.handleGet(request)
+	case http.MethodPut:
+		return h.handlePut(request)
+	case http.MethodPatch:
+		return h.handlePatch(request)
 	case http.MethodDelete:
 		return h.handleDelete(request)
 	default:
@@ -60,17 +76,130 @@ func (h *locationDetailsHandler) handleGet(request *http.Request) APIResponse {
 		return InternalServerError([]byte(err.Error()))
 	}
 
+	includeDeleted, response := parseIncludeDeleted(request)
+	if response != nil {
+		return *response
+	}
+
 	entity, err := h.locationsRepo.GetByID(id)
+	if err == sql.ErrNoRows {
+		return DefaultNotFoundError()
+	}
+
 	if err != nil {
 		return InternalServerError([]byte(err.Error()))
 	}
 
-	if entity == nil {
+	if entity == nil || (entity.DeletedAt != nil && !includeDeleted) {
 		return DefaultNotFoundError()
 	}
 
+	images, err := h.locationImagesRepo.GetByLocationID(id)
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	entity.Images = withImageUrls(images)
+
 	jsonBody, _ := json.Marshal(entity)
-	return OKContentType(jsonBody, ContentTypeJSON)
+	return entityResponse(request, jsonBody, entity.Version)
+}
+
+func (h *locationDetailsHandler) handlePut(request *http.Request) APIResponse {
+	vars := mux.Vars(request)
+	idStr, exists := vars["id"]
+	if !exists {
+		return BadRequestError([]byte("id is empty"))
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return BadRequestError([]byte(err.Error()))
+	}
+
+	var body locationHandlerPutBody
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		return decodeErrorResponse(err)
+	}
+
+	//? the id can be left out of the body but must not contradict the path
+	if body.ID != 0 && body.ID != id {
+		return BadRequestError([]byte("id in the body does not match the path"))
+	}
+
+	body.ID = id
+	return putLocation(h.locationsRepo, request, body)
+}
+
+func (h *locationDetailsHandler) handlePatch(request *http.Request) APIResponse {
+	vars := mux.Vars(request)
+	idStr, exists := vars["id"]
+	if !exists {
+		return BadRequestError([]byte("id is empty"))
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return BadRequestError([]byte(err.Error()))
+	}
+
+	current, err := h.locationsRepo.GetByID(id)
+	if err == sql.ErrNoRows || (err == nil && current.DeletedAt != nil) {
+		return DefaultNotFoundError()
+	}
+
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	if response := checkIfMatch(request, current.Version); response != nil {
+		return *response
+	}
+
+	//? the patch is applied to the same document that PUT accepts
+	document := locationHandlerPutBody{
+		ID:        current.ID,
+		City:      current.City,
+		Country:   current.Country,
+		Number:    current.Number,
+		Street:    current.Street,
+		ImageUrl:  current.ImageUrl,
+		Latitude:  current.Latitude,
+		Longitude: current.Longitude,
+	}
+
+	var body locationHandlerPutBody
+	if response := applyPatch(request, document, &body); response != nil {
+		return *response
+	}
+
+	if body.ID != current.ID {
+		return BadRequestError([]byte("location id can not be changed"))
+	}
+
+	location := repository.LocationsEntity{
+		ID:        current.ID,
+		City:      body.City,
+		Country:   body.Country,
+		Number:    body.Number,
+		Street:    body.Street,
+		ImageUrl:  body.ImageUrl,
+		Latitude:  body.Latitude,
+		Longitude: body.Longitude,
+		Version:   current.Version,
+	}
+
+	if err := validateLocation(location); err != nil {
+		return BadRequestError([]byte(err.Error()))
+	}
+
+	entity, err := h.locationsRepo.Update(location)
+	if err != nil {
+		return repositoryErrorResponse(err)
+	}
+
+	jsonBody, _ := json.Marshal(entity)
+	return entityResponse(request, jsonBody, entity.Version)
 }
 
 func (h *locationDetailsHandler) handleDelete(request *http.Request) APIResponse {
@@ -85,10 +214,29 @@ func (h *locationDetailsHandler) handleDelete(request *http.Request) APIResponse
 		return InternalServerError([]byte(err.Error()))
 	}
 
-	err = h.locationsRepo.Delete(id)
+	cascade, err := strconv.ParseBool(request.URL.Query().Get("cascade"))
+	if err != nil && request.URL.Query().Has("cascade") {
+		return BadRequestError([]byte("cascade must be true or false"))
+	}
+
+	entity, err := h.locationsRepo.GetByID(id)
+	if err == sql.ErrNoRows || (err == nil && entity.DeletedAt != nil) {
+		return DefaultNotFoundError()
+	}
+
 	if err != nil {
 		return InternalServerError([]byte(err.Error()))
 	}
 
+	if response := checkIfMatch(request, entity.Version); response != nil {
+		return *response
+	}
+
+	//? the location is only soft deleted so its images are kept for a restore
+	err = h.locationsRepo.Delete(id, entity.Version, cascade)
+	if err != nil {
+		return repositoryErrorResponse(err)
+	}
+
 	return OKContentType([]byte("true"), ContentTypeJSON)
 }
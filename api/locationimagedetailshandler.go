@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"travelagency/media"
+	"travelagency/repository"
+
+	"github.com/gorilla/mux"
+)
+
+type locationImageDetailsHandler struct {
+	locationImagesRepo *repository.LocationImagesRepo
+	store              media.BlobStore
+}
+
+type locationImageDetailsHandlerPutBody struct {
+	IsCover bool `json:"isCover"`
+}
+
+func RespondLocationImageDetails(writer http.ResponseWriter, request *http.Request) {
+	locationImagesRepo, err := repository.NewLocationImagesRepo(nil)
+	if err != nil {
+		response := InternalServerError([]byte("couldn't connect to database"))
+
+		writer.WriteHeader(response.Status)
+		writer.Write(response.Content)
+		return
+	}
+
+	actor := actorName(request)
+	locationImagesRepo.SetActor(actor)
+	locationImagesRepo.SetContext(request.Context())
+
+	handler := locationImageDetailsHandler{
+		locationImagesRepo: locationImagesRepo,
+		store:              ImageStore,
+	}
+
+	writeResponse(writer, handler.respond(request))
+}
+
+func (h *locationImageDetailsHandler) respond(request *http.Request) APIResponse {
+	switch request.Method {
+
+	case http.MethodGet:
+		return h.handleGet(request)
+	case http.MethodPut:
+		return h.handlePut(request)
+	case http.MethodDelete:
+		return h.handleDelete(request)
+	default:
+		return InternalServerError([]byte("not implemented\n"))
+	}
+}
+
+// image reads the location and image ids from the path and loads the image.
+func (h *locationImageDetailsHandler) image(request *http.Request) (*repository.LocationImagesEntity, *APIResponse) {
+	vars := mux.Vars(request)
+
+	locationID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		response := BadRequestError([]byte("invalid location id"))
+		return nil, &response
+	}
+
+	imageID, err := strconv.ParseInt(vars["imageId"], 10, 64)
+	if err != nil {
+		response := BadRequestError([]byte("invalid image id"))
+		return nil, &response
+	}
+
+	entity, err := h.locationImagesRepo.GetByID(locationID, imageID)
+	if err != nil {
+		response := InternalServerError([]byte(err.Error()))
+		return nil, &response
+	}
+
+	if entity == nil {
+		response := DefaultNotFoundError()
+		return nil, &response
+	}
+
+	return entity, nil
+}
+
+func (h *locationImageDetailsHandler) handleGet(request *http.Request) APIResponse {
+	entity, errResponse := h.image(request)
+	if errResponse != nil {
+		return *errResponse
+	}
+
+	jsonBody, _ := json.Marshal(withImageUrls([]repository.LocationImagesEntity{*entity})[0])
+	return OKNegotiated(request, jsonBody)
+}
+
+// handlePut makes the image the cover of its location, a cover can only be replaced, not unset.
+func (h *locationImageDetailsHandler) handlePut(request *http.Request) APIResponse {
+	entity, errResponse := h.image(request)
+	if errResponse != nil {
+		return *errResponse
+	}
+
+	var body locationImageDetailsHandlerPutBody
+	err := json.NewDecoder(request.Body).Decode(&body)
+	if err != nil {
+		return decodeErrorResponse(err)
+	}
+
+	if !body.IsCover {
+		return BadRequestError([]byte("set isCover on another image to replace the cover"))
+	}
+
+	err = h.locationImagesRepo.SetCover(entity.LocationId, entity.ID, mediaUrl(entity.ImageKey))
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	entity.IsCover = true
+	jsonBody, _ := json.Marshal(withImageUrls([]repository.LocationImagesEntity{*entity})[0])
+	return OKContentType(jsonBody, ContentTypeJSON)
+}
+
+func (h *locationImageDetailsHandler) handleDelete(request *http.Request) APIResponse {
+	entity, errResponse := h.image(request)
+	if errResponse != nil {
+		return *errResponse
+	}
+
+	//? the repo promotes the next image or clears the cover in the same transaction
+	err := h.locationImagesRepo.Delete(entity.LocationId, entity.ID, mediaUrl)
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	//? the blobs go only once no row refers to them anymore
+	h.store.Delete(entity.ImageKey)
+	h.store.Delete(entity.ThumbnailKey)
+
+	return OKContentType([]byte("true"), ContentTypeJSON)
+}
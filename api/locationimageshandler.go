@@ -0,0 +1,289 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"travelagency/media"
+	"travelagency/repository"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxImagesPerUpload = 10
+	maxUploadBytes     = maxImagesPerUpload*media.MaxImageBytes + 1<<20
+)
+
+type locationImagesHandler struct {
+	locationsRepo      *repository.LocationsRepo
+	locationImagesRepo *repository.LocationImagesRepo
+	store              media.BlobStore
+}
+
+type locationImagesHandlerPutBody struct {
+	Order []int64 `json:"order"`
+}
+
+func RespondLocationImages(writer http.ResponseWriter, request *http.Request) {
+	locationsRepo, err := repository.NewLocationsRepo(nil)
+	if err != nil {
+		response := InternalServerError([]byte("couldn't connect to database"))
+
+		writer.WriteHeader(response.Status)
+		writer.Write(response.Content)
+		return
+	}
+
+	locationImagesRepo, err := repository.NewLocationImagesRepo(nil)
+	if err != nil {
+		response := InternalServerError([]byte("couldn't connect to database"))
+
+		writer.WriteHeader(response.Status)
+		writer.Write(response.Content)
+		return
+	}
+
+	actor := actorName(request)
+	locationsRepo.SetActor(actor)
+	locationsRepo.SetContext(request.Context())
+	locationImagesRepo.SetActor(actor)
+	locationImagesRepo.SetContext(request.Context())
+
+	handler := locationImagesHandler{
+		locationsRepo:      locationsRepo,
+		locationImagesRepo: locationImagesRepo,
+		store:              ImageStore,
+	}
+
+	writeResponse(writer, handler.respond(request))
+}
+
+func (h *locationImagesHandler) respond(request *http.Request) APIResponse {
+	switch request.Method {
+
+	case http.MethodGet:
+		return h.handleGet(request)
+	case http.MethodPost:
+		return h.handlePost(request)
+	case http.MethodPut:
+		return h.handlePut(request)
+	default:
+		return InternalServerError([]byte("not implemented\n"))
+	}
+}
+
+// locationID reads the location id from the path and checks that the location exists and is not deleted.
+func (h *locationImagesHandler) locationID(request *http.Request) (int64, *APIResponse) {
+	id, err := strconv.ParseInt(mux.Vars(request)["id"], 10, 64)
+	if err != nil {
+		response := BadRequestError([]byte("invalid location id"))
+		return 0, &response
+	}
+
+	if location, err := h.locationsRepo.GetByID(id); err != nil || location.DeletedAt != nil {
+		response := DefaultNotFoundError()
+		return 0, &response
+	}
+
+	return id, nil
+}
+
+func (h *locationImagesHandler) handleGet(request *http.Request) APIResponse {
+	locationID, errResponse := h.locationID(request)
+	if errResponse != nil {
+		return *errResponse
+	}
+
+	data, err := h.locationImagesRepo.GetByLocationID(locationID)
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	jsonBody, _ := json.Marshal(withImageUrls(data))
+	return OKNegotiated(request, jsonBody)
+}
+
+// handlePost stores every file sent in the `image` fields of a multipart form.
+func (h *locationImagesHandler) handlePost(request *http.Request) APIResponse {
+	locationID, errResponse := h.locationID(request)
+	if errResponse != nil {
+		return *errResponse
+	}
+
+	request.Body = http.MaxBytesReader(nil, request.Body, maxUploadBytes)
+	if err := request.ParseMultipartForm(1 << 20); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return PayloadTooLargeError([]byte(fmt.Sprintf("upload is larger than %d bytes", maxUploadBytes)))
+		}
+
+		return BadRequestError([]byte(err.Error()))
+	}
+	defer request.MultipartForm.RemoveAll()
+
+	files := request.MultipartForm.File["image"]
+	if len(files) == 0 {
+		return BadRequestError([]byte("no files in the image field"))
+	}
+
+	if len(files) > maxImagesPerUpload {
+		return BadRequestError([]byte(fmt.Sprintf("at most %d images can be uploaded at once", maxImagesPerUpload)))
+	}
+
+	//? validate every file before storing any of them
+	images := make([]*media.Image, 0, len(files))
+	for _, fileHeader := range files {
+		image, err := readImage(fileHeader)
+		switch err {
+		case nil:
+		case media.ErrImageTooLarge:
+			return PayloadTooLargeError([]byte(fileHeader.Filename + ": " + err.Error()))
+		case media.ErrUnsupportedImage, media.ErrImageTooManyPixels:
+			return UnsupportedMediaTypeError([]byte(fileHeader.Filename + ": " + err.Error()))
+		default:
+			return InternalServerError([]byte(err.Error()))
+		}
+
+		images = append(images, image)
+	}
+
+	data := []repository.LocationImagesEntity{}
+	for _, image := range images {
+		entity, err := h.storeImage(locationID, image)
+		if err != nil {
+			return InternalServerError([]byte(err.Error()))
+		}
+
+		if entity.IsCover {
+			if err := h.locationImagesRepo.SetCover(locationID, entity.ID, mediaUrl(entity.ImageKey)); err != nil {
+				return InternalServerError([]byte(err.Error()))
+			}
+		}
+
+		data = append(data, *entity)
+	}
+
+	//? a single upload points at the new image, several at the images of the location
+	location := "/locations/" + strconv.FormatInt(locationID, 10) + "/images"
+	if len(data) == 1 {
+		location += "/" + strconv.FormatInt(data[0].ID, 10)
+	}
+
+	jsonBody, _ := json.Marshal(withImageUrls(data))
+	return Created(jsonBody, ContentTypeJSON, location)
+}
+
+// handlePut reorders the images of the location, the body must list every image id.
+func (h *locationImagesHandler) handlePut(request *http.Request) APIResponse {
+	locationID, errResponse := h.locationID(request)
+	if errResponse != nil {
+		return *errResponse
+	}
+
+	var body locationImagesHandlerPutBody
+	err := json.NewDecoder(request.Body).Decode(&body)
+	if err != nil {
+		return decodeErrorResponse(err)
+	}
+
+	err = h.locationImagesRepo.Reorder(locationID, body.Order)
+	if err == repository.ErrImageOrderMismatch {
+		return BadRequestError([]byte(err.Error()))
+	}
+
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	data, err := h.locationImagesRepo.GetByLocationID(locationID)
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	jsonBody, _ := json.Marshal(withImageUrls(data))
+	return OKContentType(jsonBody, ContentTypeJSON)
+}
+
+func readImage(fileHeader *multipart.FileHeader) (*media.Image, error) {
+	if fileHeader.Size > media.MaxImageBytes {
+		return nil, media.ErrImageTooLarge
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, media.MaxImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+
+	return media.ProcessImage(content)
+}
+
+// storeImage puts the image and its thumbnail in the blob store and records them,
+// the blobs are removed again when the record cannot be saved.
+func (h *locationImagesHandler) storeImage(locationID int64, image *media.Image) (*repository.LocationImagesEntity, error) {
+	name, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+
+	imageKey := fmt.Sprintf("locations/%d/%s%s", locationID, name, image.Extension)
+	thumbnailKey := fmt.Sprintf("locations/%d/%s_thumb.jpg", locationID, name)
+
+	if err := h.store.Put(imageKey, bytes.NewReader(image.Content)); err != nil {
+		return nil, err
+	}
+
+	if err := h.store.Put(thumbnailKey, bytes.NewReader(image.Thumbnail)); err != nil {
+		h.store.Delete(imageKey)
+		return nil, err
+	}
+
+	entity, err := h.locationImagesRepo.Insert(repository.LocationImagesEntity{
+		LocationId:   locationID,
+		ContentType:  image.ContentType,
+		Size:         int64(len(image.Content)),
+		Width:        image.Width,
+		Height:       image.Height,
+		ImageKey:     imageKey,
+		ThumbnailKey: thumbnailKey,
+	})
+
+	if err != nil {
+		h.store.Delete(imageKey)
+		h.store.Delete(thumbnailKey)
+		return nil, err
+	}
+
+	return entity, nil
+}
+
+func randomName() (string, error) {
+	buffer := make([]byte, 16)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buffer), nil
+}
+
+func withImageUrls(data []repository.LocationImagesEntity) []repository.LocationImagesEntity {
+	for i := range data {
+		data[i].Url = mediaUrl(data[i].ImageKey)
+		data[i].ThumbnailUrl = mediaUrl(data[i].ThumbnailKey)
+	}
+
+	return data
+}
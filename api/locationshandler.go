@@ -1,8 +1,10 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"travelagency/repository"
 )
 
@@ -11,20 +13,24 @@ type locationsHandler struct {
 }
 
 type locationHandlerPostBody struct {
-	City     string `json:"city"`
-	Country  string `json:"country"`
-	Number   string `json:"number"`
-	Street   string `json:"street"`
-	ImageUrl string `json:"imageUrl"`
+	City      string   `json:"city"`
+	Country   string   `json:"country"`
+	Number    string   `json:"number"`
+	Street    string   `json:"street"`
+	ImageUrl  string   `json:"imageUrl"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
 }
 
 type locationHandlerPutBody struct {
-	ID       int64  `json:"id"`
-	City     string `json:"city"`
-	Country  string `json:"country"`
-	Number   string `json:"number"`
-	Street   string `json:"street"`
-	ImageUrl string `json:"imageUrl"`
+	ID        int64    `json:"id"`
+	City      string   `json:"city"`
+	Country   string   `json:"country"`
+	Number    string   `json:"number"`
+	Street    string   `json:"street"`
+	ImageUrl  string   `json:"imageUrl"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
 }
 
 func RespondLocations(writer http.ResponseWriter, request *http.Request) {
@@ -37,17 +43,15 @@ func RespondLocations(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
+	actor := actorName(request)
+	locationsRepo.SetActor(actor)
+	locationsRepo.SetContext(request.Context())
+
 	handler := locationsHandler{
 		locationsRepo: locationsRepo,
 	}
 
-	response := handler.respond(request)
-	if response.ContentType != nil {
-		writer.Header().Set("Content-Type", *response.ContentType)
-	}
-
-	writer.WriteHeader(response.Status)
-	writer.Write(response.Content)
+	writeResponse(writer, handler.respond(request))
 }
 
 func (h *locationsHandler) respond(request *http.Request) APIResponse {
@@ -68,59 +72,110 @@ func (h *locationsHandler) handlePost(request *http.Request) APIResponse {
 	var body locationHandlerPostBody
 	err := json.NewDecoder(request.Body).Decode(&body)
 	if err != nil {
-		return InternalServerError([]byte(err.Error()))
+		return decodeErrorResponse(err)
 	}
 
-	entity, err := h.locationsRepo.Insert(repository.LocationsEntity{
-		City:     body.City,
-		Country:  body.Country,
-		Number:   body.Number,
-		Street:   body.Street,
-		ImageUrl: body.ImageUrl,
-	})
+	location := repository.LocationsEntity{
+		City:      body.City,
+		Country:   body.Country,
+		Number:    body.Number,
+		Street:    body.Street,
+		ImageUrl:  body.ImageUrl,
+		Latitude:  body.Latitude,
+		Longitude: body.Longitude,
+	}
+
+	if err := validateLocation(location); err != nil {
+		return BadRequestError([]byte(err.Error()))
+	}
 
+	entity, err := h.locationsRepo.Insert(location)
 	if err != nil {
 		return InternalServerError([]byte(err.Error()))
 	}
 
 	jsonBody, _ := json.Marshal(entity)
-	return OKContentType(jsonBody, ContentTypeJSON)
+	return Created(jsonBody, ContentTypeJSON, "/locations/"+strconv.FormatInt(entity.ID, 10)).WithHeader("ETag", etag(entity.Version, ContentTypeJSON, jsonBody))
 }
 
+// handlePut updates the location with the id in the body, it is kept for old clients and replaced by PUT /locations/{id}.
 func (h *locationsHandler) handlePut(request *http.Request) APIResponse {
 	var body locationHandlerPutBody
 	err := json.NewDecoder(request.Body).Decode(&body)
 	if err != nil {
-		return InternalServerError([]byte(err.Error()))
+		return decodeErrorResponse(err)
 	}
 
 	if body.ID == 0 {
 		return BadRequestError([]byte("invalid location id"))
 	}
 
-	entity, err := h.locationsRepo.Update(repository.LocationsEntity{
-		ID:       body.ID,
-		City:     body.City,
-		Country:  body.Country,
-		Number:   body.Number,
-		Street:   body.Street,
-		ImageUrl: body.ImageUrl,
-	})
+	response := putLocation(h.locationsRepo, request, body)
+	return deprecated(response, "/locations/"+strconv.FormatInt(body.ID, 10))
+}
+
+// putLocation replaces every field of the location with the body.
+func putLocation(locationsRepo *repository.LocationsRepo, request *http.Request, body locationHandlerPutBody) APIResponse {
+	current, err := locationsRepo.GetByID(body.ID)
+	if err == sql.ErrNoRows || (err == nil && current.DeletedAt != nil) {
+		return DefaultNotFoundError()
+	}
 
 	if err != nil {
 		return InternalServerError([]byte(err.Error()))
 	}
 
+	if response := checkIfMatch(request, current.Version); response != nil {
+		return *response
+	}
+
+	location := repository.LocationsEntity{
+		ID:        body.ID,
+		City:      body.City,
+		Country:   body.Country,
+		Number:    body.Number,
+		Street:    body.Street,
+		ImageUrl:  body.ImageUrl,
+		Latitude:  body.Latitude,
+		Longitude: body.Longitude,
+		Version:   current.Version,
+	}
+
+	if err := validateLocation(location); err != nil {
+		return BadRequestError([]byte(err.Error()))
+	}
+
+	entity, err := locationsRepo.Update(location)
+	if err != nil {
+		return repositoryErrorResponse(err)
+	}
+
 	jsonBody, _ := json.Marshal(entity)
-	return OKContentType(jsonBody, ContentTypeJSON)
+	return entityResponse(request, jsonBody, entity.Version)
 }
 
 func (h *locationsHandler) handleGet(request *http.Request) APIResponse {
-	data, err := h.locationsRepo.GetAll()
+	near, radiusKm, err := parseNearQuery(request)
+	if err != nil {
+		return BadRequestError([]byte(err.Error()))
+	}
+
+	includeDeleted, response := parseIncludeDeleted(request)
+	if response != nil {
+		return *response
+	}
+
+	var data []repository.LocationsEntity
+	if near != nil {
+		data, err = h.locationsRepo.GetNear(*near, radiusKm, includeDeleted)
+	} else {
+		data, err = h.locationsRepo.GetAll(includeDeleted)
+	}
+
 	if err != nil {
 		return InternalServerError([]byte(err.Error()))
 	}
 
 	jsonBody, _ := json.Marshal(data)
-	return OKContentType(jsonBody, ContentTypeJSON)
+	return OKNegotiated(request, jsonBody)
 }
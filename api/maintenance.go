@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// maintenanceRetryAfter is the Retry-After, in seconds, of the writes rejected during maintenance.
+const maintenanceRetryAfter = 30
+
+// maintenanceSwitch rejects writes while it is on. Every request holds requests shared while it runs,
+// so a restore that holds it exclusively knows no request uses the database it replaces.
+type maintenanceSwitch struct {
+	enabled  atomic.Bool
+	requests sync.RWMutex
+}
+
+var maintenance maintenanceSwitch
+
+// Maintenance answers 503 to writes while the maintenance mode is on and holds the reads back while
+// a restore replaces the database, the admin routes are always served.
+func Maintenance(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if strings.HasPrefix(routeTemplate(request), "/admin/") {
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		read := false
+		switch request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			read = true
+		}
+
+		//? checked before waiting on the lock too, a restore waiting for it must not block new writes
+		if !read && maintenance.enabled.Load() {
+			writeResponse(writer, maintenanceError())
+			return
+		}
+
+		maintenance.requests.RLock()
+		defer maintenance.requests.RUnlock()
+
+		if !read && maintenance.enabled.Load() {
+			writeResponse(writer, maintenanceError())
+			return
+		}
+
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// duringMaintenance turns the maintenance mode on, waits for the running requests and runs change
+// while new reads wait. The mode stays on afterwards when it was switched on before.
+func duringMaintenance(change func() error) error {
+	wasEnabled := maintenance.enabled.Swap(true)
+	defer func() {
+		if !wasEnabled {
+			maintenance.enabled.Store(false)
+		}
+	}()
+
+	maintenance.requests.Lock()
+	defer maintenance.requests.Unlock()
+
+	return change()
+}
+
+func maintenanceError() APIResponse {
+	return ServiceUnavailableError([]byte("the API is in maintenance, writes are rejected\n")).
+		WithHeader("Retry-After", strconv.Itoa(maintenanceRetryAfter))
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestMaintenanceHoldsReadsAndRejectsWritesDuringARestore(t *testing.T) {
+	useTestDB(t)
+
+	router := mux.NewRouter()
+	router.Use(Maintenance)
+	RegisterRoutes(router, allFeatures)
+
+	serve := func(method string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, "/categories", strings.NewReader(body))
+		request.Header.Set("Content-Type", ContentTypeJSON)
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		return response
+	}
+
+	restoring := make(chan struct{})
+	release := make(chan struct{})
+	restored := make(chan error)
+	go func() {
+		restored <- duringMaintenance(func() error {
+			close(restoring)
+			<-release
+			return nil
+		})
+	}()
+	<-restoring
+
+	read := make(chan *httptest.ResponseRecorder)
+	go func() { read <- serve(http.MethodGet, "") }()
+
+	select {
+	case response := <-read:
+		t.Fatalf("a read was served while the database was replaced: %d", response.Code)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	write := serve(http.MethodPost, `{"name": "Cruises"}`)
+	if write.Code != http.StatusServiceUnavailable || write.Header().Get("Retry-After") == "" {
+		t.Errorf("a write during the restore answered %d", write.Code)
+	}
+
+	close(release)
+	if err := <-restored; err != nil {
+		t.Fatal(err)
+	}
+
+	if response := <-read; response.Code != http.StatusOK {
+		t.Errorf("the held read answered %d: %s", response.Code, response.Body.String())
+	}
+
+	if response := serve(http.MethodPost, `{"name": "Cruises"}`); response.Code != http.StatusCreated {
+		t.Errorf("a write after the restore answered %d: %s", response.Code, response.Body.String())
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"travelagency/media"
+
+	"github.com/gorilla/mux"
+)
+
+// ImageStore keeps uploaded location images and their thumbnails.
+var ImageStore media.BlobStore = media.NewLocalBlobStore("uploads")
+
+type mediaHandler struct {
+	store media.BlobStore
+}
+
+func mediaUrl(key string) string {
+	return "/media/" + key
+}
+
+func RespondMedia(writer http.ResponseWriter, request *http.Request) {
+	handler := mediaHandler{
+		store: ImageStore,
+	}
+
+	response := handler.respond(request)
+	if response.Status == http.StatusOK {
+		//? keys are random and never reused so the content never changes
+		response = response.WithHeader("Cache-Control", "public, max-age=31536000, immutable")
+	}
+
+	writeResponse(writer, response)
+}
+
+func (h *mediaHandler) respond(request *http.Request) APIResponse {
+	switch request.Method {
+
+	case http.MethodGet:
+		return h.handleGet(request)
+	default:
+		return InternalServerError([]byte("not implemented\n"))
+	}
+}
+
+func (h *mediaHandler) handleGet(request *http.Request) APIResponse {
+	key := mux.Vars(request)["key"]
+
+	file, err := h.store.Open(key)
+	if err == media.ErrBlobNotFound || err == media.ErrInvalidKey {
+		return DefaultNotFoundError()
+	}
+
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	return OKContentType(content, mime.TypeByExtension(path.Ext(key)))
+}
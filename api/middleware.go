@@ -0,0 +1,278 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"time"
+	"travelagency/metrics"
+	"travelagency/tracing"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Logger writes the structured access and error logs of the API.
+var Logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+const (
+	requestIDHeader       = "X-Request-ID"
+	maxRequestIDLength    = 128
+	requestIDContextKey   = contextKey("requestID")
+	unmatchedRouteLogName = "unmatched"
+)
+
+type contextKey string
+
+// MaxBodyBytes limits the bodies of the routes without an entry in bodyLimits.
+var MaxBodyBytes int64 = 1 << 20
+
+// bodyLimits raises the body limit of the routes that take uploads and imports.
+var bodyLimits = map[string]int64{
+	"/locations/{id}/images": maxUploadBytes,
+	"/import/locations":      maxImportBytes,
+	"/import/holidays":       maxImportBytes,
+}
+
+// errorEnvelope is the JSON body of errors raised outside of the handlers.
+type errorEnvelope struct {
+	Error     string `json:"error"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// statusRecorder remembers the status and size of a response for the access log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	if recorder.status == 0 {
+		recorder.status = status
+	}
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *statusRecorder) Write(content []byte) (int, error) {
+	if recorder.status == 0 {
+		recorder.status = http.StatusOK
+	}
+
+	written, err := recorder.ResponseWriter.Write(content)
+	recorder.bytes += int64(written)
+	return written, err
+}
+
+// Flush lets exports stream their rows through the recorder.
+func (recorder *statusRecorder) Flush() {
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (recorder *statusRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
+
+// RequestID passes on the `X-Request-ID` of the request or generates one, it is returned in the
+// response and available to the handlers with requestIDFrom.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		id := request.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		writer.Header().Set(requestIDHeader, id)
+		request = request.WithContext(context.WithValue(request.Context(), requestIDContextKey, id))
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// requestIDFrom returns the id RequestID gave the request.
+func requestIDFrom(request *http.Request) string {
+	id, _ := request.Context().Value(requestIDContextKey).(string)
+	return id
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+
+	return hex.EncodeToString(id)
+}
+
+// AccessLog logs every request with its route template, status, latency and response size.
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		started := time.Now()
+		recorder := &statusRecorder{ResponseWriter: writer}
+
+		next.ServeHTTP(recorder, request)
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+
+		Logger.LogAttrs(request.Context(), slog.LevelInfo, "request",
+			slog.String("requestId", requestIDFrom(request)),
+			slog.String("method", request.Method),
+			slog.String("route", routeTemplate(request)),
+			slog.String("path", request.URL.Path),
+			slog.Int("status", recorder.status),
+			slog.Float64("latencyMs", float64(time.Since(started).Microseconds())/1000),
+			slog.Int64("bytes", recorder.bytes),
+		)
+	})
+}
+
+// Metrics counts requests and measures their latency per route template for `/metrics`.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		started := time.Now()
+		recorder := &statusRecorder{ResponseWriter: writer}
+
+		next.ServeHTTP(recorder, request)
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+
+		route := routeTemplate(request)
+		metrics.HTTPRequests.WithLabelValues(request.Method, route, strconv.Itoa(recorder.status)).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(request.Method, route).Observe(time.Since(started).Seconds())
+	})
+}
+
+// Tracing starts a server span for every request, continuing the trace of a W3C `traceparent`
+// header, the handlers pass its context on to the repos with SetContext.
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(request.Context(), propagation.HeaderCarrier(request.Header))
+		route := routeTemplate(request)
+		ctx, span := tracing.Tracer.Start(ctx, request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", request.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", request.URL.Path),
+				attribute.String("http.request.id", requestIDFrom(request)),
+			),
+		)
+		defer span.End()
+
+		recorder := &statusRecorder{ResponseWriter: writer}
+		next.ServeHTTP(recorder, request.WithContext(ctx))
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+
+		span.SetAttributes(attribute.Int("http.response.status_code", recorder.status))
+		if recorder.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(recorder.status))
+		}
+	})
+}
+
+// routeTemplate returns the path template of the matched route, it keeps ids out of the log keys.
+func routeTemplate(request *http.Request) string {
+	route := mux.CurrentRoute(request)
+	if route == nil {
+		return unmatchedRouteLogName
+	}
+
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return unmatchedRouteLogName
+	}
+
+	return template
+}
+
+// Recover turns a panic in a handler into a logged 500 with the JSON error envelope instead of
+// a dropped connection.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: writer}
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			//? the server aborts the response itself for this sentinel
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			Logger.LogAttrs(request.Context(), slog.LevelError, "panic",
+				slog.String("requestId", requestIDFrom(request)),
+				slog.String("method", request.Method),
+				slog.String("route", routeTemplate(request)),
+				slog.Any("error", recovered),
+				slog.String("stack", string(debug.Stack())),
+			)
+
+			//? a response that has started can not be replaced anymore, abort it so the client sees it failed
+			if recorder.status != 0 {
+				panic(http.ErrAbortHandler)
+			}
+
+			content, _ := json.Marshal(errorEnvelope{Error: http.StatusText(http.StatusInternalServerError), RequestID: requestIDFrom(request)})
+			writeResponse(recorder, APIResponse{
+				Status:      http.StatusInternalServerError,
+				Content:     content,
+				ContentType: &ContentTypeJSON,
+			})
+		}()
+
+		next.ServeHTTP(recorder, request)
+	})
+}
+
+// LimitBody rejects bodies above the limit of the route, MaxBodyBytes unless bodyLimits has an entry.
+func LimitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		limit, exists := bodyLimits[routeTemplate(request)]
+		if !exists {
+			limit = MaxBodyBytes
+		}
+
+		if request.ContentLength > limit {
+			writeResponse(writer, PayloadTooLargeError([]byte(fmt.Sprintf("the body is limited to %d bytes\n", limit))))
+			return
+		}
+
+		request.Body = http.MaxBytesReader(writer, request.Body, limit)
+		next.ServeHTTP(writer, request)
+	})
+}
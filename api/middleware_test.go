@@ -0,0 +1,67 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestLimitBodyAnswersCutOffBodiesWith413(t *testing.T) {
+	useTestDB(t)
+
+	limit := MaxBodyBytes
+	MaxBodyBytes = 64
+	t.Cleanup(func() { MaxBodyBytes = limit })
+
+	router := mux.NewRouter()
+	router.Use(LimitBody)
+	RegisterRoutes(router, allFeatures)
+
+	body := `{"name": "` + strings.Repeat("a", 128) + `"}`
+	for _, path := range []string{"/categories", "/tags", "/locations"} {
+		//? without a Content-Length the limit is only hit while the handler decodes the body
+		request := httptest.NewRequest(http.MethodPost, path, io.MultiReader(strings.NewReader(body)))
+		request.ContentLength = -1
+		request.Header.Set("Content-Type", ContentTypeJSON)
+
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		if response.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("POST %s with a cut off body answered %d: %s", path, response.Code, response.Body.String())
+		}
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/categories", strings.NewReader("{"))
+	request.Header.Set("Content-Type", ContentTypeJSON)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("POST /categories with a broken body answered %d", response.Code)
+	}
+}
+
+func TestRecoverCatchesPanicsOfTheMiddleware(t *testing.T) {
+	panicking := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			panic("middleware failed")
+		})
+	}
+
+	router := mux.NewRouter()
+	router.Use(RequestID, Recover, panicking)
+	router.Handle("/categories", http.NotFoundHandler())
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/categories", nil))
+	if response.Code != http.StatusInternalServerError {
+		t.Fatalf("the panic answered %d", response.Code)
+	}
+
+	if response.Header().Get(requestIDHeader) == "" || !strings.Contains(response.Body.String(), "requestId") {
+		t.Errorf("the 500 has no request id: %s", response.Body.String())
+	}
+}
@@ -0,0 +1,533 @@
+package api
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+// Route documents a path of the API and the handler RegisterRoutes registers for it.
+type Route struct {
+	Path       string
+	Summary    string
+	Handler    http.Handler
+	Feature    string //? the route answers 404 while the feature is disabled, empty for routes always served
+	Operations []Operation
+}
+
+// The features that can be switched off, see RegisterRoutes.
+const (
+	FeatureDocs    = "docs"
+	FeatureMetrics = "metrics"
+	FeatureSearch  = "search"
+)
+
+// Operation documents one method of a route. Body and Response are zero values of the Go types that
+// are decoded and encoded, their JSON schemas are generated from the types.
+type Operation struct {
+	Method     string
+	Summary    string
+	Query      []QueryParameter
+	Body       any
+	BodyTypes  []string //? defaults to application/json when Body is set
+	Status     int      //? defaults to 200
+	Response   any      //? nil for a response without a JSON schema
+	Types      []string //? response types, defaults to application/json when Response is set
+	Negotiated bool     //? the response is also available as CSV and XML, see OKNegotiated
+	IfMatch    bool     //? the ETag of the entity is required in If-Match
+	Admin      bool
+	Deprecated bool
+	Errors     []int
+}
+
+type QueryParameter struct {
+	Name        string
+	Type        string //? JSON schema type, defaults to string
+	Description string
+	Repeated    bool
+}
+
+// oneOf documents a response that has one of several shapes.
+type oneOf []any
+
+// jsonPatchOperation documents an operation of a JSON Patch, it is only used for the spec.
+type jsonPatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	From  string `json:"from,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// imageUpload documents the multipart form of an image upload, it is only used for the spec.
+type imageUpload struct {
+	Image [][]byte `json:"image"`
+}
+
+const openAPIVersion = "3.1.0"
+
+var (
+	openAPIOnce     sync.Once
+	openAPIDocument []byte
+	openAPIError    error
+
+	//go:embed docs.html
+	docsPage []byte
+
+	//go:embed swaggerui
+	swaggerUI embed.FS
+
+	docsAssetTypes = map[string]string{
+		".css": "text/css; charset=utf-8",
+		".js":  "text/javascript; charset=utf-8",
+	}
+
+	pathVariablePattern = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+)
+
+// RespondOpenAPI serves the OpenAPI document generated from Routes.
+func RespondOpenAPI(writer http.ResponseWriter, request *http.Request) {
+	openAPIOnce.Do(func() {
+		openAPIDocument, openAPIError = json.MarshalIndent(OpenAPIDocument(), "", "  ")
+	})
+
+	if openAPIError != nil {
+		writeResponse(writer, InternalServerError([]byte(openAPIError.Error())))
+		return
+	}
+
+	writeResponse(writer, OKContentType(openAPIDocument, ContentTypeJSON))
+}
+
+// RespondDocs serves an interactive page for the OpenAPI document.
+func RespondDocs(writer http.ResponseWriter, request *http.Request) {
+	writeResponse(writer, OKContentType(docsPage, "text/html; charset=utf-8"))
+}
+
+// RespondDocsAsset serves the bundled Swagger UI scripts and styles of the interactive page.
+func RespondDocsAsset(writer http.ResponseWriter, request *http.Request) {
+	asset := mux.Vars(request)["asset"]
+	contentType, known := docsAssetTypes[path.Ext(asset)]
+	if !known {
+		writeResponse(writer, DefaultNotFoundError())
+		return
+	}
+
+	content, err := swaggerUI.ReadFile("swaggerui/" + asset)
+	if err != nil {
+		writeResponse(writer, DefaultNotFoundError())
+		return
+	}
+
+	writeResponse(writer, OKContentType(content, contentType).WithHeader("Cache-Control", "public, max-age=86400"))
+}
+
+// RespondMethodNotAllowed answers the methods a registered path does not document, with the ones it
+// does in Allow.
+func RespondMethodNotAllowed(writer http.ResponseWriter, request *http.Request) {
+	//? mux does not tell which route matched the path, match the documented ones again
+	var methods []string
+	for _, route := range Routes {
+		var match mux.RouteMatch
+		if mux.NewRouter().Path(route.Path).Match(request, &match) {
+			methods = routeMethods(route.Path)
+			break
+		}
+	}
+
+	writeResponse(writer, MethodNotAllowedError([]byte("method not allowed\n")).
+		WithHeader("Allow", strings.Join(append(methods, http.MethodOptions), ", ")))
+}
+
+// RegisterRoutes adds the handlers of Routes to the router for their documented methods and OPTIONS,
+// which the CORS middleware answers. Routes of disabled features answer 404.
+func RegisterRoutes(router *mux.Router, features map[string]bool) {
+	for _, route := range Routes {
+		handler := route.Handler
+		if route.Feature != "" && !features[route.Feature] {
+			handler = http.NotFoundHandler()
+		}
+
+		methods := []string{http.MethodOptions}
+		for _, operation := range route.Operations {
+			methods = append(methods, operation.Method)
+		}
+
+		router.Handle(route.Path, handler).Methods(methods...)
+	}
+}
+
+// OpenAPIDocument generates the OpenAPI document of Routes.
+func OpenAPIDocument() map[string]any {
+	schemas := schemaBuilder{components: map[string]any{}}
+
+	paths := map[string]any{}
+	for _, route := range Routes {
+		item := map[string]any{}
+		if route.Summary != "" {
+			item["summary"] = route.Summary
+		}
+
+		if parameters := pathParameters(route.Path); len(parameters) > 0 {
+			item["parameters"] = parameters
+		}
+
+		for _, operation := range route.Operations {
+			item[strings.ToLower(operation.Method)] = schemas.operation(operation)
+		}
+
+		paths[openAPIPath(route.Path)] = item
+	}
+
+	return map[string]any{
+		"openapi": openAPIVersion,
+		"info": map[string]any{
+			"title":   "Travel Agency API",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]any{
+				"apiKey": map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+		"security": []any{map[string]any{}, map[string]any{"apiKey": []string{}}},
+	}
+}
+
+// CheckRoutes returns an error when the methods and paths registered on the router and the documented
+// Routes disagree.
+func CheckRoutes(router *mux.Router) error {
+	documented := map[string]bool{}
+	for _, route := range Routes {
+		for _, operation := range route.Operations {
+			documented[operation.Method+" "+openAPIPath(route.Path)] = true
+		}
+	}
+
+	registered := map[string]bool{}
+	var problems []string
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil //? routes without a path, like middleware only routes, are not documented
+		}
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			problems = append(problems, openAPIPath(template)+" is registered for every method")
+			return nil
+		}
+
+		for _, method := range methods {
+			if method == http.MethodOptions {
+				continue //? answered by the CORS middleware, not documented
+			}
+
+			operation := method + " " + openAPIPath(template)
+			registered[operation] = true
+			if !documented[operation] {
+				problems = append(problems, operation+" is not documented in the OpenAPI routes")
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for operation := range documented {
+		if !registered[operation] {
+			problems = append(problems, operation+" is documented but not registered")
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return errors.New(strings.Join(problems, "\n"))
+	}
+
+	return nil
+}
+
+// openAPIPath removes the patterns of mux path variables, /media/{key:.+} becomes /media/{key}.
+func openAPIPath(path string) string {
+	return pathVariablePattern.ReplaceAllString(path, "{$1}")
+}
+
+func pathParameters(path string) []any {
+	parameters := []any{}
+	for _, match := range pathVariablePattern.FindAllStringSubmatch(path, -1) {
+		schema := map[string]any{"type": "string"}
+		if match[1] == "id" || strings.HasSuffix(match[1], "Id") {
+			schema = map[string]any{"type": "integer", "format": "int64"}
+		}
+
+		parameters = append(parameters, map[string]any{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   schema,
+		})
+	}
+
+	return parameters
+}
+
+func (b *schemaBuilder) operation(operation Operation) map[string]any {
+	document := map[string]any{}
+	if operation.Summary != "" {
+		document["summary"] = operation.Summary
+	}
+
+	if operation.Deprecated {
+		document["deprecated"] = true
+	}
+
+	if operation.Admin {
+		document["security"] = []any{map[string]any{"apiKey": []string{}}}
+	}
+
+	parameters := []any{}
+	for _, query := range operation.Query {
+		schema := map[string]any{"type": query.Type}
+		if query.Type == "" {
+			schema["type"] = "string"
+		}
+
+		if query.Repeated {
+			schema = map[string]any{"type": "array", "items": schema}
+		}
+
+		parameter := map[string]any{"name": query.Name, "in": "query", "schema": schema}
+		if query.Description != "" {
+			parameter["description"] = query.Description
+		}
+
+		parameters = append(parameters, parameter)
+	}
+
+	if operation.IfMatch {
+		parameters = append(parameters, headerParameter("If-Match", "ETag of the entity version that is changed", true))
+	}
+
+	if operation.Method == http.MethodPost {
+		parameters = append(parameters, headerParameter("Idempotency-Key", "answers retries with the stored response", false))
+	}
+
+	if len(parameters) > 0 {
+		document["parameters"] = parameters
+	}
+
+	if operation.Body != nil {
+		bodyTypes := operation.BodyTypes
+		if len(bodyTypes) == 0 {
+			bodyTypes = []string{ContentTypeJSON}
+		}
+
+		content := map[string]any{}
+		for _, bodyType := range bodyTypes {
+			schema := b.schema(reflect.TypeOf(operation.Body))
+			switch bodyType {
+			case ContentTypeJSONPatch:
+				schema = b.schema(reflect.TypeOf([]jsonPatchOperation{}))
+			case ContentTypeCSV, ContentTypeNDJSON:
+				schema = map[string]any{"type": "string"}
+			}
+
+			content[bodyType] = map[string]any{"schema": schema}
+		}
+
+		document["requestBody"] = map[string]any{"required": true, "content": content}
+	}
+
+	status := operation.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	success := map[string]any{"description": http.StatusText(status)}
+	if content := b.responseContent(operation); len(content) > 0 {
+		success["content"] = content
+	}
+
+	responses := map[string]any{strconv.Itoa(status): success}
+	errorStatuses := append([]int{}, operation.Errors...)
+	if operation.Admin {
+		errorStatuses = append(errorStatuses, http.StatusUnauthorized, http.StatusForbidden)
+	}
+
+	if operation.IfMatch {
+		errorStatuses = append(errorStatuses, http.StatusPreconditionFailed, http.StatusPreconditionRequired)
+	}
+
+	if operation.Negotiated {
+		errorStatuses = append(errorStatuses, http.StatusNotAcceptable)
+	}
+
+	for _, errorStatus := range errorStatuses {
+		responses[strconv.Itoa(errorStatus)] = map[string]any{"description": http.StatusText(errorStatus)}
+	}
+
+	document["responses"] = responses
+	return document
+}
+
+func (b *schemaBuilder) responseContent(operation Operation) map[string]any {
+	content := map[string]any{}
+
+	types := operation.Types
+	if len(types) == 0 && operation.Response != nil {
+		types = []string{ContentTypeJSON}
+	}
+
+	for _, contentType := range types {
+		schema := map[string]any{"type": "string"}
+		if contentType == ContentTypeJSON && operation.Response != nil {
+			schema = b.response(operation.Response)
+		} else if strings.HasPrefix(contentType, "image/") {
+			schema["format"] = "binary"
+		}
+
+		content[contentType] = map[string]any{"schema": schema}
+	}
+
+	if operation.Negotiated {
+		content[ContentTypeCSV] = map[string]any{"schema": map[string]any{"type": "string"}}
+		content[ContentTypeXML] = map[string]any{"schema": map[string]any{"type": "string"}}
+	}
+
+	return content
+}
+
+func (b *schemaBuilder) response(response any) map[string]any {
+	shapes, ok := response.(oneOf)
+	if !ok {
+		return b.schema(reflect.TypeOf(response))
+	}
+
+	schemas := []any{}
+	for _, shape := range shapes {
+		schemas = append(schemas, b.schema(reflect.TypeOf(shape)))
+	}
+
+	return map[string]any{"oneOf": schemas}
+}
+
+func headerParameter(name string, description string, required bool) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"in":          "header",
+		"description": description,
+		"required":    required,
+		"schema":      map[string]any{"type": "string"},
+	}
+}
+
+// schemaBuilder generates JSON schemas of Go types the way encoding/json encodes them, named structs
+// are added to the components and referenced.
+type schemaBuilder struct {
+	components map[string]any
+}
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	switch {
+	case t == rawMessageType:
+		return map[string]any{}
+	case t.Kind() == reflect.Pointer:
+		return nullable(b.schema(t.Elem()))
+	case t.Kind() == reflect.Struct:
+		return b.structSchema(t)
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return map[string]any{"type": "string", "format": "binary"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case t.Kind() == reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case t.Kind() == reflect.String:
+		return map[string]any{"type": "string"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		schema := map[string]any{"type": "integer"}
+		if t.Kind() == reflect.Int64 {
+			schema["format"] = "int64"
+		}
+		return schema
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]any{"type": "number"}
+	default:
+		return map[string]any{} //? interfaces accept any value
+	}
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]any {
+	name := t.Name()
+	ref := map[string]any{"$ref": "#/components/schemas/" + name}
+	if _, exists := b.components[name]; exists {
+		return ref
+	}
+
+	b.components[name] = map[string]any{} //? placeholder so recursive types end
+
+	properties := map[string]any{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		fieldName, options, _ := strings.Cut(tag, ",")
+		if fieldName == "" {
+			fieldName = field.Name
+		}
+
+		properties[fieldName] = b.schema(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, fieldName)
+		}
+	}
+
+	b.components[name] = map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+
+	return ref
+}
+
+// nullable allows null in addition to the schema.
+func nullable(schema map[string]any) map[string]any {
+	if schemaType, ok := schema["type"].(string); ok {
+		nullableSchema := map[string]any{"type": []string{schemaType, "null"}}
+		for key, value := range schema {
+			if key != "type" {
+				nullableSchema[key] = value
+			}
+		}
+		return nullableSchema
+	}
+
+	return map[string]any{"oneOf": []any{schema, map[string]any{"type": "null"}}}
+}
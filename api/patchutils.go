@@ -0,0 +1,72 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+const (
+	ContentTypeMergePatch = "application/merge-patch+json"
+	ContentTypeJSONPatch  = "application/json-patch+json"
+)
+
+// maxPatchBytes limits the size of PATCH bodies, a patch only touches a single entity.
+const maxPatchBytes = 1 << 20
+
+// applyPatch applies the RFC 7396 merge patch or RFC 6902 JSON Patch of the request to document
+// and decodes the result into patched. Fields that the document does not have are rejected.
+func applyPatch(request *http.Request, document any, patched any) *APIResponse {
+	mediaType, _, err := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	if err != nil || (mediaType != ContentTypeMergePatch && mediaType != ContentTypeJSONPatch) {
+		response := UnsupportedMediaTypeError([]byte("Content-Type must be " + ContentTypeMergePatch + " or " + ContentTypeJSONPatch))
+		return &response
+	}
+
+	patch, err := io.ReadAll(http.MaxBytesReader(nil, request.Body, maxPatchBytes))
+	if err != nil {
+		response := PayloadTooLargeError([]byte(err.Error()))
+		return &response
+	}
+
+	original, err := json.Marshal(document)
+	if err != nil {
+		response := InternalServerError([]byte(err.Error()))
+		return &response
+	}
+
+	var result []byte
+	if mediaType == ContentTypeMergePatch {
+		result, err = jsonpatch.MergePatch(original, patch)
+	} else {
+		var operations jsonpatch.Patch
+		operations, err = jsonpatch.DecodePatch(patch)
+		if err == nil {
+			result, err = operations.Apply(original)
+		}
+	}
+
+	if errors.Is(err, jsonpatch.ErrTestFailed) {
+		response := ConflictError([]byte(err.Error()))
+		return &response
+	}
+
+	if err != nil {
+		response := BadRequestError([]byte(err.Error()))
+		return &response
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(result))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(patched); err != nil {
+		response := BadRequestError([]byte(err.Error()))
+		return &response
+	}
+
+	return nil
+}
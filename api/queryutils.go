@@ -0,0 +1,125 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"travelagency/repository"
+)
+
+const defaultRadiusKm = 50.0
+
+// parseHolidaysFilter reads the holiday filters shared by `/holidays` and `/search`.
+func parseHolidaysFilter(request *http.Request) (repository.HolidaysFilter, error) {
+	query := request.URL.Query()
+
+	near, radiusKm, err := parseNearQuery(request)
+	if err != nil {
+		return repository.HolidaysFilter{}, err
+	}
+
+	sort := query.Get("sort")
+	if sort != "" && sort != repository.HolidaysSortRating {
+		return repository.HolidaysFilter{}, errors.New("sort must be rating")
+	}
+
+	return repository.HolidaysFilter{
+		Location:  query.Get("location"),
+		StartDate: query.Get("startDate"),
+		Duration:  query.Get("duration"),
+		Category:  query.Get("category"),
+		Tags:      query["tag"],
+		Sort:      sort,
+		Near:      near,
+		RadiusKm:  radiusKm,
+	}, nil
+}
+
+// parseNearQuery reads the `near=lat,lng` and `radiusKm` query parameters.
+// It returns a nil point when `near` is not present.
+func parseNearQuery(request *http.Request) (*repository.GeoPoint, float64, error) {
+	query := request.URL.Query()
+	near := query.Get("near")
+	if near == "" {
+		if query.Get("radiusKm") != "" {
+			return nil, 0, errors.New("radiusKm requires near")
+		}
+
+		return nil, 0, nil
+	}
+
+	parts := strings.Split(near, ",")
+	if len(parts) != 2 {
+		return nil, 0, errors.New("near must be in the format lat,lng")
+	}
+
+	latitude, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return nil, 0, errors.New("invalid latitude in near")
+	}
+
+	longitude, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return nil, 0, errors.New("invalid longitude in near")
+	}
+
+	if err := validateCoordinates(&latitude, &longitude); err != nil {
+		return nil, 0, err
+	}
+
+	radiusKm := defaultRadiusKm
+	if radiusStr := query.Get("radiusKm"); radiusStr != "" {
+		radiusKm, err = strconv.ParseFloat(radiusStr, 64)
+		if err != nil || radiusKm <= 0 {
+			return nil, 0, errors.New("radiusKm must be a positive number")
+		}
+	}
+
+	return &repository.GeoPoint{Latitude: latitude, Longitude: longitude}, radiusKm, nil
+}
+
+// validateCoordinates checks that latitude and longitude are either both set and in range or both missing.
+func validateCoordinates(latitude *float64, longitude *float64) error {
+	if latitude == nil && longitude == nil {
+		return nil
+	}
+
+	if latitude == nil || longitude == nil {
+		return errors.New("latitude and longitude must be set together")
+	}
+
+	if *latitude < -90 || *latitude > 90 {
+		return errors.New("latitude must be between -90 and 90")
+	}
+
+	if *longitude < -180 || *longitude > 180 {
+		return errors.New("longitude must be between -180 and 180")
+	}
+
+	return nil
+}
+
+// parseIncludeDeleted reads the includeDeleted query parameter, only admins may see soft deleted entities.
+func parseIncludeDeleted(request *http.Request) (bool, *APIResponse) {
+	value := request.URL.Query().Get("includeDeleted")
+	if value == "" {
+		return false, nil
+	}
+
+	includeDeleted, err := strconv.ParseBool(value)
+	if err != nil {
+		response := BadRequestError([]byte("includeDeleted must be true or false"))
+		return false, &response
+	}
+
+	if !includeDeleted {
+		return false, nil
+	}
+
+	if response := requireAdmin(request); response != nil {
+		return false, response
+	}
+
+	return true, nil
+}
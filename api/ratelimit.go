@@ -0,0 +1,95 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+	"travelagency/metrics"
+	"travelagency/ratelimit"
+)
+
+// RateLimitPolicy gives every API key, or client IP without one, a budget for reads and one for writes.
+type RateLimitPolicy struct {
+	Store ratelimit.Store //? nil turns rate limiting off
+	Read  ratelimit.Limit
+	Write ratelimit.Limit
+}
+
+// RateLimits is set from the `rateLimit` configuration.
+var RateLimits RateLimitPolicy
+
+// rateLimitExempt are the routes probes and scrapers call, they are never limited.
+var rateLimitExempt = map[string]bool{
+	"/livez":   true,
+	"/readyz":  true,
+	"/health":  true,
+	"/metrics": true,
+}
+
+// RateLimit answers 429 once the client used up the budget of the request, every limited response
+// carries the `RateLimit-*` headers.
+func RateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if RateLimits.Store == nil || rateLimitExempt[routeTemplate(request)] {
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		budget, limit := "write", RateLimits.Write
+		switch request.Method {
+		case http.MethodGet, http.MethodHead:
+			budget, limit = "read", RateLimits.Read
+		}
+
+		result, err := RateLimits.Store.Take(request.Context(), budget+"|"+clientKey(request), limit, time.Now())
+		if err != nil {
+			//? an unavailable store must not take the API down with it
+			Logger.LogAttrs(request.Context(), slog.LevelError, "rate limit store",
+				slog.String("requestId", requestIDFrom(request)),
+				slog.String("error", err.Error()),
+			)
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		header := writer.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Burst, ceilSeconds(time.Duration(float64(limit.Burst)/limit.Rate*float64(time.Second)))))
+
+		if !result.Allowed {
+			metrics.RateLimited.WithLabelValues(budget).Inc()
+
+			retryAfter := ceilSeconds(result.RetryAfter)
+			writeResponse(writer, TooManyRequestsError([]byte(fmt.Sprintf("too many %ss, retry in %d seconds\n", budget, retryAfter))).
+				WithHeader("Retry-After", strconv.Itoa(retryAfter)))
+			return
+		}
+
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// clientKey identifies the client by the principal of its API key or by its IP, the rate limit
+// buckets and the idempotency keys belong to it.
+func clientKey(request *http.Request) string {
+	if principal := authenticate(request); principal != nil {
+		return "key:" + principal.Name
+	}
+
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		host = request.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+func ceilSeconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"travelagency/ratelimit"
+
+	"github.com/gorilla/mux"
+)
+
+// useRateLimits sets the policy for the test and returns a router with the rate limit middleware
+// whose handlers answer 200.
+func useRateLimits(t *testing.T, policy RateLimitPolicy) *mux.Router {
+	t.Helper()
+
+	previous := RateLimits
+	RateLimits = policy
+	t.Cleanup(func() { RateLimits = previous })
+
+	ok := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+	})
+
+	router := mux.NewRouter()
+	router.Use(RateLimit)
+	router.Handle("/holidays", ok)
+	router.Handle("/livez", ok)
+	return router
+}
+
+func serveFrom(router *mux.Router, method string, path string, remoteAddr string, apiKey string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, nil)
+	request.RemoteAddr = remoteAddr
+	if apiKey != "" {
+		request.Header.Set("X-API-Key", apiKey)
+	}
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	return response
+}
+
+func TestRateLimitAnswers429WithRetryAfter(t *testing.T) {
+	router := useRateLimits(t, RateLimitPolicy{
+		Store: ratelimit.NewMemoryStore(),
+		Read:  ratelimit.PerMinute(60, 2),
+		Write: ratelimit.PerMinute(6, 1),
+	})
+
+	const client = "192.0.2.1:50000"
+	for i := 0; i < 2; i++ {
+		response := serveFrom(router, http.MethodGet, "/holidays", client, "")
+		if response.Code != http.StatusOK {
+			t.Fatalf("read %d answered %d", i+1, response.Code)
+		}
+
+		if remaining := response.Header().Get("RateLimit-Remaining"); remaining != strconv.Itoa(1-i) {
+			t.Errorf("read %d has %s remaining", i+1, remaining)
+		}
+	}
+
+	limited := serveFrom(router, http.MethodGet, "/holidays", client, "")
+	if limited.Code != http.StatusTooManyRequests {
+		t.Fatalf("the read over the burst answered %d", limited.Code)
+	}
+
+	if retryAfter := limited.Header().Get("Retry-After"); retryAfter != "1" {
+		t.Errorf("the limited read has Retry-After %q", retryAfter)
+	}
+
+	if policy := limited.Header().Get("RateLimit-Policy"); policy != "2;w=2" {
+		t.Errorf("the policy is %q", policy)
+	}
+
+	//? writes have a budget of their own
+	if response := serveFrom(router, http.MethodPost, "/holidays", client, ""); response.Code != http.StatusOK {
+		t.Errorf("the first write after the reads answered %d", response.Code)
+	}
+
+	write := serveFrom(router, http.MethodPost, "/holidays", client, "")
+	if write.Code != http.StatusTooManyRequests || write.Header().Get("Retry-After") != "10" {
+		t.Errorf("the second write answered %d with Retry-After %q", write.Code, write.Header().Get("Retry-After"))
+	}
+
+	//? probes are never limited
+	for i := 0; i < 5; i++ {
+		response := serveFrom(router, http.MethodGet, "/livez", client, "")
+		if response.Code != http.StatusOK || response.Header().Get("RateLimit-Limit") != "" {
+			t.Fatalf("probe %d answered %d with the limit %q", i+1, response.Code, response.Header().Get("RateLimit-Limit"))
+		}
+	}
+}
+
+func TestRateLimitKeysClientsByPrincipalOrIP(t *testing.T) {
+	useAdminKey(t)
+	router := useRateLimits(t, RateLimitPolicy{
+		Store: ratelimit.NewMemoryStore(),
+		Read:  ratelimit.PerMinute(60, 1),
+		Write: ratelimit.PerMinute(60, 1),
+	})
+
+	//? the key is shared by every IP it is sent from
+	if response := serveFrom(router, http.MethodGet, "/holidays", "192.0.2.1:50000", "adm"); response.Code != http.StatusOK {
+		t.Fatalf("the first read with the key answered %d", response.Code)
+	}
+
+	if response := serveFrom(router, http.MethodGet, "/holidays", "198.51.100.7:50000", "adm"); response.Code != http.StatusTooManyRequests {
+		t.Errorf("the key from another IP answered %d", response.Code)
+	}
+
+	//? anonymous clients are told apart by their IP, not their port
+	if response := serveFrom(router, http.MethodGet, "/holidays", "192.0.2.1:50001", ""); response.Code != http.StatusOK {
+		t.Errorf("the IP of the key holder was limited by the key: %d", response.Code)
+	}
+
+	if response := serveFrom(router, http.MethodGet, "/holidays", "192.0.2.1:50002", ""); response.Code != http.StatusTooManyRequests {
+		t.Errorf("another port of the same IP answered %d", response.Code)
+	}
+
+	tests := []struct {
+		remoteAddr string
+		apiKey     string
+		key        string
+	}{
+		{"192.0.2.1:50000", "adm", "key:alice"},
+		{"192.0.2.1:50000", "", "ip:192.0.2.1"},
+		{"192.0.2.1:50000", "unknown", "ip:192.0.2.1"},
+		{"[2001:db8::1]:50000", "", "ip:2001:db8::1"},
+		{"192.0.2.1", "", "ip:192.0.2.1"},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodGet, "/holidays", nil)
+		request.RemoteAddr = test.remoteAddr
+		if test.apiKey != "" {
+			request.Header.Set("X-API-Key", test.apiKey)
+		}
+
+		if key := clientKey(request); key != test.key {
+			t.Errorf("%s with the key %q is keyed as %s, expected %s", test.remoteAddr, test.apiKey, key, test.key)
+		}
+	}
+}
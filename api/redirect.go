@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RedirectToHTTPS answers every request with a permanent redirect to the same path on the HTTPS
+// address, the host of the request is kept so that the redirect works behind any name.
+func RedirectToHTTPS(httpsAddress string) http.Handler {
+	defaultHost, port, _ := net.SplitHostPort(httpsAddress)
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		host := request.Host
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			host = hostname
+		}
+
+		//? an IPv6 host without a port keeps its brackets, JoinHostPort adds them again
+		host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+		if host == "" {
+			host = defaultHost
+		}
+
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+
+		http.Redirect(writer, request, "https://"+host+request.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
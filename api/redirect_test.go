@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		name         string
+		httpsAddress string
+		host         string
+		location     string
+	}{
+		{"host with port", "example.com:8443", "travel.example:8080", "https://travel.example:8443/holidays?page=2"},
+		{"host without port", "example.com:8443", "travel.example", "https://travel.example:8443/holidays?page=2"},
+		{"default port", "example.com:443", "travel.example:8080", "https://travel.example/holidays?page=2"},
+		{"IPv6 with port", "example.com:8443", "[::1]:8080", "https://[::1]:8443/holidays?page=2"},
+		{"IPv6 without port", "example.com:8443", "[::1]", "https://[::1]:8443/holidays?page=2"},
+		{"IPv6 on the default port", "example.com:443", "[::1]", "https://[::1]/holidays?page=2"},
+		{"default host", "example.com:8443", "", "https://example.com:8443/holidays?page=2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/holidays?page=2", nil)
+			request.Host = test.host
+
+			response := httptest.NewRecorder()
+			RedirectToHTTPS(test.httpsAddress).ServeHTTP(response, request)
+
+			if response.Code != http.StatusPermanentRedirect {
+				t.Errorf("answered %d", response.Code)
+			}
+
+			if location := response.Header().Get("Location"); location != test.location {
+				t.Errorf("redirected to %s, expected %s", location, test.location)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"strconv"
@@ -23,17 +24,15 @@ func RespondReservationDetails(writer http.ResponseWriter, request *http.Request
 		return
 	}
 
+	actor := actorName(request)
+	reservationsRepo.SetActor(actor)
+	reservationsRepo.SetContext(request.Context())
+
 	handler := reservationDetailsHandler{
 		reservationRepo: reservationsRepo,
 	}
 
-	response := handler.respond(request)
-	if response.ContentType != nil {
-		writer.Header().Set("Content-Type", *response.ContentType)
-	}
-
-	writer.WriteHeader(response.Status)
-	writer.Write(response.Content)
+	writeResponse(writer, handler.respond(request))
 }
 
 func (h *reservationDetailsHandler) respond(request *http.Request) APIResponse {
@@ -41,6 +40,10 @@ func (h *reservationDetailsHandler) respond(request *http.Request) APIResponse {
 
 	case http.MethodGet:
 		return h.handleGet(request)
+	case http.MethodPut:
+		return h.handlePut(request)
+	case http.MethodPatch:
+		return h.handlePatch(request)
 	case http.MethodDelete:
 		return h.handleDelete(request)
 	default:
@@ -60,17 +63,115 @@ func (h *reservationDetailsHandler) handleGet(request *http.Request) APIResponse
 		return InternalServerError([]byte(err.Error()))
 	}
 
+	includeDeleted, response := parseIncludeDeleted(request)
+	if response != nil {
+		return *response
+	}
+
 	entity, err := h.reservationRepo.GetById(id)
+	if err == sql.ErrNoRows {
+		return DefaultNotFoundError()
+	}
+
 	if err != nil {
 		return InternalServerError([]byte(err.Error()))
 	}
 
-	if entity == nil {
+	if entity == nil || (entity.DeletedAt != nil && !includeDeleted) {
+		return DefaultNotFoundError()
+	}
+
+	jsonBody, _ := json.Marshal(entity)
+	return entityResponse(request, jsonBody, entity.Version)
+}
+
+func (h *reservationDetailsHandler) handlePut(request *http.Request) APIResponse {
+	vars := mux.Vars(request)
+	idStr, exists := vars["id"]
+	if !exists {
+		return BadRequestError([]byte("id is empty"))
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return BadRequestError([]byte(err.Error()))
+	}
+
+	var body reservationsHandlerPutBody
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		return decodeErrorResponse(err)
+	}
+
+	//? the id can be left out of the body but must not contradict the path
+	if body.ID != 0 && body.ID != id {
+		return BadRequestError([]byte("id in the body does not match the path"))
+	}
+
+	body.ID = id
+	return putReservation(h.reservationRepo, request, body)
+}
+
+func (h *reservationDetailsHandler) handlePatch(request *http.Request) APIResponse {
+	vars := mux.Vars(request)
+	idStr, exists := vars["id"]
+	if !exists {
+		return BadRequestError([]byte("id is empty"))
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return BadRequestError([]byte(err.Error()))
+	}
+
+	current, err := h.reservationRepo.GetById(id)
+	if err == sql.ErrNoRows || (err == nil && current.DeletedAt != nil) {
 		return DefaultNotFoundError()
 	}
 
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	if response := checkIfMatch(request, current.Version); response != nil {
+		return *response
+	}
+
+	//? the patch is applied to the same document that PUT accepts
+	document := reservationsHandlerPutBody{
+		ID:          current.ID,
+		ContactName: current.ContactName,
+		PhoneNumber: current.PhoneNumber,
+		Holiday:     current.HolidayId,
+	}
+
+	var body reservationsHandlerPutBody
+	if response := applyPatch(request, document, &body); response != nil {
+		return *response
+	}
+
+	if body.ID != current.ID {
+		return BadRequestError([]byte("reservation id can not be changed"))
+	}
+
+	reservation := repository.ReservationsEntity{
+		ID:          current.ID,
+		ContactName: body.ContactName,
+		PhoneNumber: body.PhoneNumber,
+		HolidayId:   body.Holiday,
+		Version:     current.Version,
+	}
+
+	if err := validateReservation(reservation); err != nil {
+		return BadRequestError([]byte(err.Error()))
+	}
+
+	entity, err := h.reservationRepo.Update(reservation)
+	if err != nil {
+		return repositoryErrorResponse(err)
+	}
+
 	jsonBody, _ := json.Marshal(entity)
-	return OKContentType(jsonBody, ContentTypeJSON)
+	return entityResponse(request, jsonBody, entity.Version)
 }
 
 func (h *reservationDetailsHandler) handleDelete(request *http.Request) APIResponse {
@@ -85,10 +186,23 @@ func (h *reservationDetailsHandler) handleDelete(request *http.Request) APIRespo
 		return InternalServerError([]byte(err.Error()))
 	}
 
-	err = h.reservationRepo.Delete(id)
+	entity, err := h.reservationRepo.GetById(id)
+	if err == sql.ErrNoRows || (err == nil && entity.DeletedAt != nil) {
+		return DefaultNotFoundError()
+	}
+
 	if err != nil {
 		return InternalServerError([]byte(err.Error()))
 	}
 
+	if response := checkIfMatch(request, entity.Version); response != nil {
+		return *response
+	}
+
+	err = h.reservationRepo.Delete(id, entity.Version)
+	if err != nil {
+		return repositoryErrorResponse(err)
+	}
+
 	return OKContentType([]byte("true"), ContentTypeJSON)
 }
@@ -1,8 +1,10 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"travelagency/repository"
 )
 
@@ -33,17 +35,15 @@ func RespondReservations(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
+	actor := actorName(request)
+	reservationsRepo.SetActor(actor)
+	reservationsRepo.SetContext(request.Context())
+
 	handler := reservationsHandler{
 		reservationsRepo: reservationsRepo,
 	}
 
-	response := handler.respond(request)
-	if response.ContentType != nil {
-		writer.Header().Set("Content-Type", *response.ContentType)
-	}
-
-	writer.WriteHeader(response.Status)
-	writer.Write(response.Content)
+	writeResponse(writer, handler.respond(request))
 }
 
 func (h *reservationsHandler) respond(request *http.Request) APIResponse {
@@ -64,51 +64,91 @@ func (h *reservationsHandler) handlePost(request *http.Request) APIResponse {
 	var body reservationsHandlerPostBody
 	err := json.NewDecoder(request.Body).Decode(&body)
 	if err != nil {
-		return InternalServerError([]byte(err.Error()))
+		return decodeErrorResponse(err)
 	}
 
-	entity, err := h.reservationsRepo.Insert(repository.ReservationsEntity{
+	reservation := repository.ReservationsEntity{
 		ContactName: body.ContactName,
 		PhoneNumber: body.PhoneNumber,
 		HolidayId:   body.Holiday,
-	})
+	}
+
+	if err := validateReservation(reservation); err != nil {
+		return BadRequestError([]byte(err.Error()))
+	}
 
+	entity, err := h.reservationsRepo.Insert(reservation)
 	if err != nil {
-		return InternalServerError([]byte(err.Error()))
+		return repositoryErrorResponse(err)
 	}
 
 	jsonBody, _ := json.Marshal(entity)
-	return OKContentType(jsonBody, ContentTypeJSON)
+	return Created(jsonBody, ContentTypeJSON, "/reservations/"+strconv.FormatInt(entity.ID, 10)).WithHeader("ETag", etag(entity.Version, ContentTypeJSON, jsonBody))
 }
 
+// handlePut updates the reservation with the id in the body, it is kept for old clients and replaced by PUT /reservations/{id}.
 func (h *reservationsHandler) handlePut(request *http.Request) APIResponse {
 	var body reservationsHandlerPutBody
 	err := json.NewDecoder(request.Body).Decode(&body)
+	if err != nil {
+		return decodeErrorResponse(err)
+	}
+
+	if body.ID == 0 {
+		return BadRequestError([]byte("invalid reservation id"))
+	}
+
+	response := putReservation(h.reservationsRepo, request, body)
+	return deprecated(response, "/reservations/"+strconv.FormatInt(body.ID, 10))
+}
+
+// putReservation replaces every field of the reservation with the body.
+func putReservation(reservationsRepo *repository.ReservationsRepo, request *http.Request, body reservationsHandlerPutBody) APIResponse {
+	current, err := reservationsRepo.GetById(body.ID)
+	if err == sql.ErrNoRows || (err == nil && current.DeletedAt != nil) {
+		return DefaultNotFoundError()
+	}
+
 	if err != nil {
 		return InternalServerError([]byte(err.Error()))
 	}
 
-	entity, err := h.reservationsRepo.Update(repository.ReservationsEntity{
+	if response := checkIfMatch(request, current.Version); response != nil {
+		return *response
+	}
+
+	reservation := repository.ReservationsEntity{
 		ID:          body.ID,
 		ContactName: body.ContactName,
 		PhoneNumber: body.PhoneNumber,
 		HolidayId:   body.Holiday,
-	})
+		Version:     current.Version,
+	}
+
+	if err := validateReservation(reservation); err != nil {
+		return BadRequestError([]byte(err.Error()))
+	}
 
+	entity, err := reservationsRepo.Update(reservation)
 	if err != nil {
-		return InternalServerError([]byte(err.Error()))
+		return repositoryErrorResponse(err)
 	}
 
 	jsonBody, _ := json.Marshal(entity)
-	return OKContentType(jsonBody, ContentTypeJSON)
+	return entityResponse(request, jsonBody, entity.Version)
 }
 
 func (h *reservationsHandler) handleGet(request *http.Request) APIResponse {
-	data, err := h.reservationsRepo.GetAll()
+	includeDeleted, response := parseIncludeDeleted(request)
+	if response != nil {
+		return *response
+	}
+
+	data, err := h.reservationsRepo.GetAll(includeDeleted)
 	if err != nil {
 		return InternalServerError([]byte(err.Error()))
 	}
 
 	jsonBody, _ := json.Marshal(data)
-	return OKContentType(jsonBody, ContentTypeJSON)
+	return OKNegotiated(request, jsonBody)
 }
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"travelagency/repository"
+
+	"github.com/gorilla/mux"
+)
+
+type restoreHandler struct {
+	restore func(id int64) error
+}
+
+func RespondLocationRestore(writer http.ResponseWriter, request *http.Request) {
+	locationsRepo, err := repository.NewLocationsRepo(nil)
+	if err != nil {
+		response := InternalServerError([]byte("couldn't connect to database"))
+
+		writer.WriteHeader(response.Status)
+		writer.Write(response.Content)
+		return
+	}
+
+	locationsRepo.SetActor(actorName(request))
+	locationsRepo.SetContext(request.Context())
+
+	handler := restoreHandler{
+		restore: locationsRepo.Restore,
+	}
+
+	writeResponse(writer, handler.respond(request))
+}
+
+func RespondHolidayRestore(writer http.ResponseWriter, request *http.Request) {
+	holidaysRepo, err := repository.NewHolidaysRepo(nil)
+	if err != nil {
+		response := InternalServerError([]byte("couldn't connect to database"))
+
+		writer.WriteHeader(response.Status)
+		writer.Write(response.Content)
+		return
+	}
+
+	holidaysRepo.SetActor(actorName(request))
+	holidaysRepo.SetContext(request.Context())
+
+	handler := restoreHandler{
+		restore: holidaysRepo.Restore,
+	}
+
+	writeResponse(writer, handler.respond(request))
+}
+
+func RespondReservationRestore(writer http.ResponseWriter, request *http.Request) {
+	reservationsRepo, err := repository.NewReservationsRepo(nil)
+	if err != nil {
+		response := InternalServerError([]byte("couldn't connect to database"))
+
+		writer.WriteHeader(response.Status)
+		writer.Write(response.Content)
+		return
+	}
+
+	reservationsRepo.SetActor(actorName(request))
+	reservationsRepo.SetContext(request.Context())
+
+	handler := restoreHandler{
+		restore: reservationsRepo.Restore,
+	}
+
+	writeResponse(writer, handler.respond(request))
+}
+
+func (h *restoreHandler) respond(request *http.Request) APIResponse {
+	if response := requireAdmin(request); response != nil {
+		return *response
+	}
+
+	switch request.Method {
+
+	case http.MethodPost:
+		return h.handlePost(request)
+	default:
+		return InternalServerError([]byte("not implemented\n"))
+	}
+}
+
+func (h *restoreHandler) handlePost(request *http.Request) APIResponse {
+	vars := mux.Vars(request)
+	idStr, exists := vars["id"]
+	if !exists {
+		return BadRequestError([]byte("id is empty"))
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return BadRequestError([]byte(err.Error()))
+	}
+
+	if err := h.restore(id); err != nil {
+		return repositoryErrorResponse(err)
+	}
+
+	return OKContentType([]byte("true"), ContentTypeJSON)
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"travelagency/repository"
+
+	"github.com/gorilla/mux"
+)
+
+type reviewDetailsHandler struct {
+	reviewsRepo *repository.ReviewsRepo
+}
+
+type reviewDetailsHandlerPutBody struct {
+	Status string `json:"status"`
+}
+
+func RespondReviewDetails(writer http.ResponseWriter, request *http.Request) {
+	reviewsRepo, err := repository.NewReviewsRepo(nil)
+	if err != nil {
+		response := InternalServerError([]byte("couldn't connect to database"))
+
+		writer.WriteHeader(response.Status)
+		writer.Write(response.Content)
+		return
+	}
+
+	actor := actorName(request)
+	reviewsRepo.SetActor(actor)
+	reviewsRepo.SetContext(request.Context())
+
+	handler := reviewDetailsHandler{
+		reviewsRepo: reviewsRepo,
+	}
+
+	writeResponse(writer, handler.respond(request))
+}
+
+func (h *reviewDetailsHandler) respond(request *http.Request) APIResponse {
+	switch request.Method {
+
+	case http.MethodGet:
+		return h.handleGet(request)
+	case http.MethodPut, http.MethodDelete:
+		//? only admins moderate, reviewers could otherwise approve their own reviews
+		if response := requireAdmin(request); response != nil {
+			return *response
+		}
+
+		if request.Method == http.MethodPut {
+			return h.handlePut(request)
+		}
+
+		return h.handleDelete(request)
+	default:
+		return InternalServerError([]byte("not implemented\n"))
+	}
+}
+
+func (h *reviewDetailsHandler) handleGet(request *http.Request) APIResponse {
+	vars := mux.Vars(request)
+	idStr, exists := vars["id"]
+	if !exists {
+		return BadRequestError([]byte("id is empty"))
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	entity, err := h.reviewsRepo.GetByID(id)
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	//? reviews that are not approved yet are only shown to admins
+	if entity == nil || (entity.Status != repository.ReviewStatusApproved && !isAdmin(request)) {
+		return DefaultNotFoundError()
+	}
+
+	jsonBody, _ := json.Marshal(entity)
+	return OKNegotiated(request, jsonBody)
+}
+
+// handlePut moderates the review by changing its status.
+func (h *reviewDetailsHandler) handlePut(request *http.Request) APIResponse {
+	vars := mux.Vars(request)
+	idStr, exists := vars["id"]
+	if !exists {
+		return BadRequestError([]byte("id is empty"))
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	var body reviewDetailsHandlerPutBody
+	err = json.NewDecoder(request.Body).Decode(&body)
+	if err != nil {
+		return decodeErrorResponse(err)
+	}
+
+	if !validReviewStatus(body.Status) {
+		return BadRequestError([]byte("invalid review status"))
+	}
+
+	entity, err := h.reviewsRepo.SetStatus(id, body.Status)
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	if entity == nil {
+		return DefaultNotFoundError()
+	}
+
+	jsonBody, _ := json.Marshal(entity)
+	return OKContentType(jsonBody, ContentTypeJSON)
+}
+
+func (h *reviewDetailsHandler) handleDelete(request *http.Request) APIResponse {
+	vars := mux.Vars(request)
+	idStr, exists := vars["id"]
+	if !exists {
+		return BadRequestError([]byte("id is empty"))
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	err = h.reviewsRepo.Delete(id)
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	return OKContentType([]byte("true"), ContentTypeJSON)
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"travelagency/repository"
+)
+
+type reviewsHandler struct {
+	reviewsRepo *repository.ReviewsRepo
+}
+
+type reviewsHandlerPostBody struct {
+	Reservation int64  `json:"reservation"`
+	PhoneNumber string `json:"phoneNumber"`
+	Rating      int    `json:"rating"`
+	Text        string `json:"text"`
+}
+
+func RespondReviews(writer http.ResponseWriter, request *http.Request) {
+	reviewsRepo, err := repository.NewReviewsRepo(nil)
+	if err != nil {
+		response := InternalServerError([]byte("couldn't connect to database"))
+
+		writer.WriteHeader(response.Status)
+		writer.Write(response.Content)
+		return
+	}
+
+	actor := actorName(request)
+	reviewsRepo.SetActor(actor)
+	reviewsRepo.SetContext(request.Context())
+
+	handler := reviewsHandler{
+		reviewsRepo: reviewsRepo,
+	}
+
+	writeResponse(writer, handler.respond(request))
+}
+
+func (h *reviewsHandler) respond(request *http.Request) APIResponse {
+	switch request.Method {
+
+	case http.MethodGet:
+		return h.handleGet(request)
+	case http.MethodPost:
+		return h.handlePost(request)
+	default:
+		return InternalServerError([]byte("not implemented\n"))
+	}
+}
+
+func (h *reviewsHandler) handlePost(request *http.Request) APIResponse {
+	var body reviewsHandlerPostBody
+	err := json.NewDecoder(request.Body).Decode(&body)
+	if err != nil {
+		return decodeErrorResponse(err)
+	}
+
+	if body.Rating < 1 || body.Rating > 5 {
+		return BadRequestError([]byte("rating must be between 1 and 5"))
+	}
+
+	entity, err := h.reviewsRepo.Insert(repository.ReviewsEntity{
+		ReservationId: body.Reservation,
+		Rating:        body.Rating,
+		Text:          body.Text,
+	}, body.PhoneNumber)
+
+	switch err {
+	case nil:
+	case repository.ErrReservationNotFound, repository.ErrReviewerMismatch:
+		return BadRequestError([]byte(err.Error()))
+	case repository.ErrReservationNotCompleted, repository.ErrReservationAlreadyReviewed:
+		return ConflictError([]byte(err.Error()))
+	default:
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	jsonBody, _ := json.Marshal(entity)
+	return Created(jsonBody, ContentTypeJSON, "/reviews/"+strconv.FormatInt(entity.ID, 10))
+}
+
+// handleGet lists approved reviews unless an admin requests another status or `all`.
+func (h *reviewsHandler) handleGet(request *http.Request) APIResponse {
+	query := request.URL.Query()
+	filter := repository.ReviewsFilter{
+		Status: repository.ReviewStatusApproved,
+	}
+
+	switch status := query.Get("status"); {
+	case status == "":
+	case status == "all":
+		filter.Status = ""
+	case validReviewStatus(status):
+		filter.Status = status
+	default:
+		return BadRequestError([]byte("invalid review status"))
+	}
+
+	if filter.Status != repository.ReviewStatusApproved && !isAdmin(request) {
+		return ForbiddenError([]byte("only admins list reviews that are not approved\n"))
+	}
+
+	var err error
+	if holiday := query.Get("holiday"); holiday != "" {
+		filter.HolidayId, err = strconv.ParseInt(holiday, 10, 64)
+		if err != nil {
+			return BadRequestError([]byte("invalid holiday id"))
+		}
+	}
+
+	if location := query.Get("location"); location != "" {
+		filter.LocationId, err = strconv.ParseInt(location, 10, 64)
+		if err != nil {
+			return BadRequestError([]byte("invalid location id"))
+		}
+	}
+
+	data, err := h.reviewsRepo.GetAll(filter)
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	jsonBody, _ := json.Marshal(data)
+	return OKNegotiated(request, jsonBody)
+}
+
+func validReviewStatus(status string) bool {
+	switch status {
+	case repository.ReviewStatusPending, repository.ReviewStatusApproved, repository.ReviewStatusRejected:
+		return true
+	default:
+		return false
+	}
+}
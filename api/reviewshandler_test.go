@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+var anonymous = map[string]string{"X-API-Key": "", "Content-Type": ContentTypeJSON}
+
+// usePendingReview reviews a holiday that has ended and returns the path of the pending review.
+func usePendingReview(t *testing.T) (*mux.Router, string) {
+	t.Helper()
+
+	router := useFixtures(t)
+	post := func(path string, body string) *httptest.ResponseRecorder {
+		response := serveRequest(router, http.MethodPost, path, body, anonymous)
+		if response.Code != http.StatusCreated {
+			t.Fatalf("POST %s answered %d: %s", path, response.Code, response.Body.String())
+		}
+
+		return response
+	}
+
+	var created struct {
+		ID int64 `json:"id"`
+	}
+
+	holiday := post("/holidays", `{"location": 2, "title": "Lisbon last winter", "startDate": "2020-01-10", "duration": 3, "price": "300", "freeSlots": 5}`)
+	json.Unmarshal(holiday.Body.Bytes(), &created)
+
+	reservation := post("/reservations", `{"contactName": "Ana", "phoneNumber": "+351912345678", "holiday": `+strconv.FormatInt(created.ID, 10)+`}`)
+	json.Unmarshal(reservation.Body.Bytes(), &created)
+
+	review := post("/reviews", `{"reservation": `+strconv.FormatInt(created.ID, 10)+`, "phoneNumber": "+351912345678", "rating": 5, "text": "lovely"}`)
+	return router, review.Header().Get("Location")
+}
+
+func TestOnlyAdminsModerateReviews(t *testing.T) {
+	router, review := usePendingReview(t)
+
+	if response := serveRequest(router, http.MethodPut, review, `{"status": "approved"}`, anonymous); response.Code != http.StatusUnauthorized {
+		t.Errorf("an anonymous moderation answered %d: %s", response.Code, response.Body.String())
+	}
+
+	if response := serveRequest(router, http.MethodDelete, review, "", anonymous); response.Code != http.StatusUnauthorized {
+		t.Errorf("an anonymous delete answered %d: %s", response.Code, response.Body.String())
+	}
+
+	//? the pending review is hidden from anonymous callers
+	if response := serveRequest(router, http.MethodGet, review, "", anonymous); response.Code != http.StatusNotFound {
+		t.Errorf("an anonymous GET of the pending review answered %d", response.Code)
+	}
+
+	if response := serveRequest(router, http.MethodGet, review, "", nil); response.Code != http.StatusOK {
+		t.Errorf("an admin GET of the pending review answered %d", response.Code)
+	}
+
+	if response := serveRequest(router, http.MethodPut, review, `{"status": "approved"}`, map[string]string{"Content-Type": ContentTypeJSON}); response.Code != http.StatusOK {
+		t.Fatalf("the admin moderation answered %d: %s", response.Code, response.Body.String())
+	}
+
+	if response := serveRequest(router, http.MethodGet, review, "", anonymous); response.Code != http.StatusOK {
+		t.Errorf("an anonymous GET of the approved review answered %d", response.Code)
+	}
+
+	if response := serveRequest(router, http.MethodDelete, review, "", nil); response.Code != http.StatusOK {
+		t.Errorf("the admin delete answered %d: %s", response.Code, response.Body.String())
+	}
+}
+
+func TestOnlyAdminsListReviewsThatAreNotApproved(t *testing.T) {
+	router, _ := usePendingReview(t)
+
+	if response := serveRequest(router, http.MethodGet, "/reviews", "", anonymous); response.Code != http.StatusOK {
+		t.Errorf("listing the approved reviews answered %d", response.Code)
+	}
+
+	for _, status := range []string{"pending", "rejected", "all"} {
+		if response := serveRequest(router, http.MethodGet, "/reviews?status="+status, "", anonymous); response.Code != http.StatusForbidden {
+			t.Errorf("an anonymous listing of %s reviews answered %d", status, response.Code)
+		}
+
+		response := serveRequest(router, http.MethodGet, "/reviews?status="+status, "", nil)
+		if response.Code != http.StatusOK {
+			t.Errorf("an admin listing of %s reviews answered %d", status, response.Code)
+		}
+
+		var reviews []map[string]any
+		json.Unmarshal(response.Body.Bytes(), &reviews)
+		if status != "rejected" && len(reviews) != 1 {
+			t.Errorf("the admin listing of %s reviews has %d reviews", status, len(reviews))
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"net/http"
+	"travelagency/metrics"
+	"travelagency/repository"
+)
+
+var (
+	includeDeletedQuery = QueryParameter{Name: "includeDeleted", Type: "boolean", Description: "include soft deleted entities, admins only"}
+	nearQueries         = []QueryParameter{
+		{Name: "near", Description: "lat,lng to search around"},
+		{Name: "radiusKm", Type: "number", Description: "radius around near, 50 by default"},
+	}
+	holidayFilterQueries = append([]QueryParameter{
+		{Name: "location"},
+		{Name: "startDate"},
+		{Name: "duration"},
+		{Name: "category"},
+		{Name: "tag", Repeated: true, Description: "holidays must have every tag"},
+		{Name: "sort", Description: "rating"},
+	}, nearQueries...)
+	importQueries = []QueryParameter{
+		{Name: "dryRun", Type: "boolean", Description: "check the rows without storing them"},
+		{Name: "mode", Description: "atomic ( default ) or bestEffort"},
+	}
+	exportQueries = []QueryParameter{
+		{Name: "format", Description: "ndjson ( default ) or csv"},
+		includeDeletedQuery,
+	}
+
+	patchBodyTypes  = []string{ContentTypeMergePatch, ContentTypeJSONPatch}
+	importBodyTypes = []string{ContentTypeCSV, ContentTypeNDJSON}
+	exportTypes     = []string{ContentTypeNDJSON, ContentTypeCSV}
+)
+
+// Routes documents every path and its handler, RegisterRoutes adds them to the router and the OpenAPI
+// document is generated from them. It is filled in init because RespondOpenAPI reads it.
+var Routes []Route
+
+func init() {
+	Routes = []Route{
+		{Path: "/livez", Handler: http.HandlerFunc(RespondLivez), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "check that the server runs", Response: healthStatus{}},
+		}},
+		{Path: "/readyz", Handler: http.HandlerFunc(RespondReadyz), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "check the database, its schema version and free disk space", Response: healthStatus{}, Errors: []int{http.StatusServiceUnavailable}},
+		}},
+		{Path: "/health", Handler: http.HandlerFunc(RespondHealth), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "check the database, its schema version and free disk space", Response: healthStatus{}, Deprecated: true, Errors: []int{http.StatusServiceUnavailable}},
+		}},
+		{Path: "/admin/reset", Handler: http.HandlerFunc(RespondAdminReset), Operations: []Operation{
+			{Method: http.MethodPost, Summary: "snapshot the database, recreate it and optionally seed the sample data, disabled in production", Body: adminResetPostBody{}, Response: adminResetResponse{}, Admin: true, Errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError}},
+		}},
+		{Path: "/admin/backups", Handler: http.HandlerFunc(RespondAdminBackups), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "list the backups and the snapshots taken before resets and restores, newest first", Response: []repository.Snapshot{}, Admin: true},
+			{Method: http.MethodPost, Summary: "back up the running database and remove the backups beyond the retention", Status: http.StatusCreated, Response: repository.Snapshot{}, Admin: true, Errors: []int{http.StatusInternalServerError}},
+		}},
+		{Path: "/admin/backups/{name}/restore", Handler: http.HandlerFunc(RespondAdminBackupRestore), Operations: []Operation{
+			{Method: http.MethodPost, Summary: "snapshot the database and replace it with the backup, writes are rejected with 503 meanwhile", Body: adminBackupRestorePostBody{}, Response: adminBackupRestoreResponse{}, Admin: true, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError}},
+		}},
+		{Path: "/admin/maintenance", Handler: http.HandlerFunc(RespondAdminMaintenance), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "whether writes are rejected for maintenance", Response: adminMaintenanceBody{}, Admin: true},
+			{Method: http.MethodPut, Summary: "switch the maintenance mode, writes are answered with 503 while it is on", Body: adminMaintenanceBody{}, Response: adminMaintenanceBody{}, Admin: true, Errors: []int{http.StatusBadRequest}},
+		}},
+		{Path: "/openapi.json", Handler: http.HandlerFunc(RespondOpenAPI), Feature: FeatureDocs, Operations: []Operation{
+			{Method: http.MethodGet, Summary: "this document", Types: []string{ContentTypeJSON}},
+		}},
+		{Path: "/docs", Handler: http.HandlerFunc(RespondDocs), Feature: FeatureDocs, Operations: []Operation{
+			{Method: http.MethodGet, Summary: "interactive documentation", Types: []string{"text/html"}},
+		}},
+		{Path: "/docs/{asset}", Handler: http.HandlerFunc(RespondDocsAsset), Feature: FeatureDocs, Operations: []Operation{
+			{Method: http.MethodGet, Summary: "scripts and styles of the interactive documentation", Types: []string{"text/css", "text/javascript"}, Errors: []int{http.StatusNotFound}},
+		}},
+		{Path: "/metrics", Handler: metrics.Handler(), Feature: FeatureMetrics, Operations: []Operation{
+			{Method: http.MethodGet, Summary: "Prometheus metrics", Types: []string{"text/plain"}},
+		}},
+
+		{Path: "/locations", Handler: http.HandlerFunc(RespondLocations), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "list locations", Query: append([]QueryParameter{includeDeletedQuery}, nearQueries...), Response: []repository.LocationsEntity{}, Negotiated: true, Errors: []int{http.StatusBadRequest}},
+			{Method: http.MethodPost, Summary: "create a location", Body: locationHandlerPostBody{}, Status: http.StatusCreated, Response: repository.LocationsEntity{}, Errors: []int{http.StatusBadRequest}},
+			{Method: http.MethodPut, Summary: "replace the location with the id in the body", Body: locationHandlerPutBody{}, Response: repository.LocationsEntity{}, IfMatch: true, Deprecated: true, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		}},
+		{Path: "/locations/{id}", Handler: http.HandlerFunc(RespondLocationDetails), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "get a location with its images", Query: []QueryParameter{includeDeletedQuery}, Response: repository.LocationsEntity{}, Negotiated: true, Errors: []int{http.StatusNotModified, http.StatusNotFound}},
+			{Method: http.MethodPut, Summary: "replace a location", Body: locationHandlerPutBody{}, Response: repository.LocationsEntity{}, IfMatch: true, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+			{Method: http.MethodPatch, Summary: "patch a location", Body: locationHandlerPutBody{}, BodyTypes: patchBodyTypes, Response: repository.LocationsEntity{}, IfMatch: true, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnsupportedMediaType}},
+			{Method: http.MethodDelete, Summary: "soft delete a location", Query: []QueryParameter{{Name: "cascade", Type: "boolean", Description: "also delete the holidays of the location"}}, Response: true, IfMatch: true, Errors: []int{http.StatusNotFound, http.StatusConflict}},
+		}},
+		{Path: "/locations/{id}/images", Handler: http.HandlerFunc(RespondLocationImages), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "list the images of a location", Response: []repository.LocationImagesEntity{}, Negotiated: true, Errors: []int{http.StatusNotFound}},
+			{Method: http.MethodPost, Summary: "upload images", Body: imageUpload{}, BodyTypes: []string{"multipart/form-data"}, Status: http.StatusCreated, Response: []repository.LocationImagesEntity{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType}},
+			{Method: http.MethodPut, Summary: "reorder the images", Body: locationImagesHandlerPutBody{}, Response: []repository.LocationImagesEntity{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		}},
+		{Path: "/locations/{id}/images/{imageId}", Handler: http.HandlerFunc(RespondLocationImageDetails), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "get an image", Response: repository.LocationImagesEntity{}, Negotiated: true, Errors: []int{http.StatusNotFound}},
+			{Method: http.MethodPut, Summary: "make the image the cover", Body: locationImageDetailsHandlerPutBody{}, Response: repository.LocationImagesEntity{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+			{Method: http.MethodDelete, Summary: "delete an image", Response: true, Errors: []int{http.StatusNotFound}},
+		}},
+		{Path: "/locations/{id}/restore", Handler: http.HandlerFunc(RespondLocationRestore), Operations: []Operation{
+			{Method: http.MethodPost, Summary: "restore a soft deleted location", Response: true, Admin: true, Errors: []int{http.StatusNotFound, http.StatusConflict}},
+		}},
+
+		{Path: "/holidays", Handler: http.HandlerFunc(RespondHolidays), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "list holidays", Query: append(append([]QueryParameter{}, holidayFilterQueries...), includeDeletedQuery, QueryParameter{Name: "facets", Type: "boolean", Description: "answer with the holidays and their facets"}), Response: oneOf{[]repository.HolidaysEntity{}, holidaysHandlerFacetedResponse{}}, Negotiated: true, Errors: []int{http.StatusBadRequest}},
+			{Method: http.MethodPost, Summary: "create a holiday", Body: holidayHandlerPostBody{}, Status: http.StatusCreated, Response: repository.HolidaysEntity{}, Errors: []int{http.StatusBadRequest, http.StatusConflict}},
+			{Method: http.MethodPut, Summary: "replace the holiday with the id in the body", Body: holidayHandlerPutBody{}, Response: repository.HolidaysEntity{}, IfMatch: true, Deprecated: true, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+		}},
+		{Path: "/holidays/{id}", Handler: http.HandlerFunc(RespondHolidayDetails), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "get a holiday", Query: []QueryParameter{includeDeletedQuery}, Response: repository.HolidaysEntity{}, Negotiated: true, Errors: []int{http.StatusNotModified, http.StatusNotFound}},
+			{Method: http.MethodPut, Summary: "replace a holiday", Body: holidayHandlerPutBody{}, Response: repository.HolidaysEntity{}, IfMatch: true, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+			{Method: http.MethodPatch, Summary: "patch a holiday", Body: holidayHandlerPutBody{}, BodyTypes: patchBodyTypes, Response: repository.HolidaysEntity{}, IfMatch: true, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnsupportedMediaType}},
+			{Method: http.MethodDelete, Summary: "soft delete a holiday", Response: true, IfMatch: true, Errors: []int{http.StatusNotFound, http.StatusConflict}},
+		}},
+		{Path: "/holidays/{id}/restore", Handler: http.HandlerFunc(RespondHolidayRestore), Operations: []Operation{
+			{Method: http.MethodPost, Summary: "restore a soft deleted holiday", Response: true, Admin: true, Errors: []int{http.StatusNotFound, http.StatusConflict}},
+		}},
+
+		{Path: "/categories", Handler: http.HandlerFunc(RespondCategories), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "list categories", Response: []repository.CategoriesEntity{}, Negotiated: true},
+			{Method: http.MethodPost, Summary: "create a category", Body: categoryHandlerPostBody{}, Status: http.StatusCreated, Response: repository.CategoriesEntity{}, Errors: []int{http.StatusBadRequest}},
+			{Method: http.MethodPut, Summary: "rename the category with the id in the body", Body: categoryHandlerPutBody{}, Response: repository.CategoriesEntity{}, Deprecated: true, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		}},
+		{Path: "/categories/{id}", Handler: http.HandlerFunc(RespondCategoryDetails), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "get a category", Response: repository.CategoriesEntity{}, Negotiated: true, Errors: []int{http.StatusNotFound}},
+			{Method: http.MethodPut, Summary: "rename a category", Body: categoryHandlerPutBody{}, Response: repository.CategoriesEntity{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+			{Method: http.MethodDelete, Summary: "delete a category", Response: true, Errors: []int{http.StatusNotFound, http.StatusConflict}},
+		}},
+
+		{Path: "/tags", Handler: http.HandlerFunc(RespondTags), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "list tags", Response: []repository.TagsEntity{}, Negotiated: true},
+			{Method: http.MethodPost, Summary: "create a tag", Body: tagHandlerPostBody{}, Status: http.StatusCreated, Response: repository.TagsEntity{}, Errors: []int{http.StatusBadRequest}},
+			{Method: http.MethodPut, Summary: "rename the tag with the id in the body", Body: tagHandlerPutBody{}, Response: repository.TagsEntity{}, Deprecated: true, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		}},
+		{Path: "/tags/{id}", Handler: http.HandlerFunc(RespondTagDetails), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "get a tag", Response: repository.TagsEntity{}, Negotiated: true, Errors: []int{http.StatusNotFound}},
+			{Method: http.MethodPut, Summary: "rename a tag", Body: tagHandlerPutBody{}, Response: repository.TagsEntity{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+			{Method: http.MethodDelete, Summary: "delete a tag", Response: true, Errors: []int{http.StatusNotFound}},
+		}},
+
+		{Path: "/reservations", Handler: http.HandlerFunc(RespondReservations), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "list reservations", Query: []QueryParameter{includeDeletedQuery}, Response: []repository.ReservationsEntity{}, Negotiated: true},
+			{Method: http.MethodPost, Summary: "reserve a slot of a holiday", Body: reservationsHandlerPostBody{}, Status: http.StatusCreated, Response: repository.ReservationsEntity{}, Errors: []int{http.StatusBadRequest, http.StatusConflict}},
+			{Method: http.MethodPut, Summary: "replace the reservation with the id in the body", Body: reservationsHandlerPutBody{}, Response: repository.ReservationsEntity{}, IfMatch: true, Deprecated: true, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+		}},
+		{Path: "/reservations/{id}", Handler: http.HandlerFunc(RespondReservationDetails), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "get a reservation", Query: []QueryParameter{includeDeletedQuery}, Response: repository.ReservationsEntity{}, Negotiated: true, Errors: []int{http.StatusNotModified, http.StatusNotFound}},
+			{Method: http.MethodPut, Summary: "replace a reservation", Body: reservationsHandlerPutBody{}, Response: repository.ReservationsEntity{}, IfMatch: true, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+			{Method: http.MethodPatch, Summary: "patch a reservation", Body: reservationsHandlerPutBody{}, BodyTypes: patchBodyTypes, Response: repository.ReservationsEntity{}, IfMatch: true, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnsupportedMediaType}},
+			{Method: http.MethodDelete, Summary: "soft delete a reservation", Response: true, IfMatch: true, Errors: []int{http.StatusNotFound}},
+		}},
+		{Path: "/reservations/{id}/restore", Handler: http.HandlerFunc(RespondReservationRestore), Operations: []Operation{
+			{Method: http.MethodPost, Summary: "restore a soft deleted reservation", Response: true, Admin: true, Errors: []int{http.StatusNotFound, http.StatusConflict}},
+		}},
+
+		{Path: "/reviews", Handler: http.HandlerFunc(RespondReviews), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "list reviews", Query: []QueryParameter{{Name: "holiday", Type: "integer"}, {Name: "location", Type: "integer"}, {Name: "status", Description: "pending, approved ( default ), rejected or all, admins only unless approved"}}, Response: []repository.ReviewsEntity{}, Negotiated: true, Errors: []int{http.StatusBadRequest, http.StatusForbidden}},
+			{Method: http.MethodPost, Summary: "review a holiday that has ended", Body: reviewsHandlerPostBody{}, Status: http.StatusCreated, Response: repository.ReviewsEntity{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+		}},
+		{Path: "/reviews/{id}", Handler: http.HandlerFunc(RespondReviewDetails), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "get a review", Response: repository.ReviewsEntity{}, Negotiated: true, Errors: []int{http.StatusNotFound}},
+			{Method: http.MethodPut, Summary: "moderate a review", Body: reviewDetailsHandlerPutBody{}, Response: repository.ReviewsEntity{}, Admin: true, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+			{Method: http.MethodDelete, Summary: "delete a review", Response: true, Admin: true, Errors: []int{http.StatusNotFound}},
+		}},
+
+		{Path: "/search", Handler: http.HandlerFunc(RespondSearch), Feature: FeatureSearch, Operations: []Operation{
+			{Method: http.MethodGet, Summary: "full-text search of holidays", Query: append([]QueryParameter{{Name: "q", Description: "words to search for"}}, holidayFilterQueries...), Response: []repository.SearchResult{}, Negotiated: true, Errors: []int{http.StatusBadRequest, http.StatusNotImplemented}},
+		}},
+
+		{Path: "/import/locations", Handler: http.HandlerFunc(RespondImportLocations), Operations: []Operation{
+			{Method: http.MethodPost, Summary: "import locations", Query: importQueries, Body: "", BodyTypes: importBodyTypes, Response: repository.ImportResult{}, Errors: []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity}},
+		}},
+		{Path: "/import/holidays", Handler: http.HandlerFunc(RespondImportHolidays), Operations: []Operation{
+			{Method: http.MethodPost, Summary: "import holidays", Query: importQueries, Body: "", BodyTypes: importBodyTypes, Response: repository.ImportResult{}, Errors: []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity}},
+		}},
+		{Path: "/export/locations", Handler: http.HandlerFunc(RespondExportLocations), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "export locations", Query: exportQueries, Types: exportTypes, Errors: []int{http.StatusBadRequest}},
+		}},
+		{Path: "/export/holidays", Handler: http.HandlerFunc(RespondExportHolidays), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "export holidays", Query: append(append([]QueryParameter{}, exportQueries...), holidayFilterQueries...), Types: exportTypes, Errors: []int{http.StatusBadRequest}},
+		}},
+
+		{Path: "/audit", Handler: http.HandlerFunc(RespondAudit), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "read the audit log", Query: []QueryParameter{{Name: "entity"}, {Name: "id", Type: "integer"}, {Name: "actor"}}, Response: []repository.AuditEntity{}, Negotiated: true, Admin: true, Errors: []int{http.StatusBadRequest}},
+		}},
+
+		{Path: "/media/{key:.+}", Handler: http.HandlerFunc(RespondMedia), Operations: []Operation{
+			{Method: http.MethodGet, Summary: "download an uploaded image", Types: []string{"image/*"}, Errors: []int{http.StatusNotFound}},
+		}},
+	}
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"travelagency/media"
+	"travelagency/repository"
+
+	"github.com/gorilla/mux"
+)
+
+var allFeatures = map[string]bool{FeatureDocs: true, FeatureMetrics: true, FeatureSearch: true}
+
+// useTestDB points the repositories at a new database in a temporary directory.
+func useTestDB(t *testing.T) {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "test.db")
+	repository.Configure(file, "file:"+file+"?_foreign_keys=1&_busy_timeout=5000&_txlock=immediate")
+	t.Cleanup(func() {
+		repository.CloseDB()
+		repository.Configure("sqlite.db", "file:sqlite.db?_foreign_keys=1&_busy_timeout=5000&_txlock=immediate")
+	})
+
+	if err := repository.CreateDB(); err != nil {
+		t.Fatalf("creating the database: %v", err)
+	}
+}
+
+// useAdminKey authenticates the requests sent with the X-API-Key `adm` as an admin.
+func useAdminKey(t *testing.T) {
+	t.Helper()
+
+	previous := APIKeys
+	APIKeys = ParseAPIKeys("adm:alice:admin")
+	t.Cleanup(func() { APIKeys = previous })
+}
+
+func TestRegisteredRoutesAreDocumented(t *testing.T) {
+	router := mux.NewRouter()
+	RegisterRoutes(router, allFeatures)
+
+	if err := CheckRoutes(router); err != nil {
+		t.Fatalf("the registered routes and the OpenAPI routes disagree:\n%v", err)
+	}
+}
+
+func TestCheckRoutesReportsDisagreements(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(router *mux.Router)
+		problem  string
+	}{
+		{
+			name: "undocumented path",
+			register: func(router *mux.Router) {
+				RegisterRoutes(router, allFeatures)
+				router.HandleFunc("/undocumented", RespondLivez).Methods(http.MethodGet)
+			},
+			problem: "GET /undocumented is not documented",
+		},
+		{
+			name: "undocumented method",
+			register: func(router *mux.Router) {
+				RegisterRoutes(router, allFeatures)
+				router.HandleFunc("/livez", RespondLivez).Methods(http.MethodDelete)
+			},
+			problem: "DELETE /livez is not documented",
+		},
+		{
+			name: "path registered for every method",
+			register: func(router *mux.Router) {
+				RegisterRoutes(router, allFeatures)
+				router.HandleFunc("/anything", RespondLivez)
+			},
+			problem: "/anything is registered for every method",
+		},
+		{
+			name: "documented but not registered",
+			register: func(router *mux.Router) {
+				router.HandleFunc("/livez", RespondLivez).Methods(http.MethodGet)
+			},
+			problem: "GET /readyz is documented but not registered",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := mux.NewRouter()
+			test.register(router)
+
+			err := CheckRoutes(router)
+			if err == nil || !strings.Contains(err.Error(), test.problem) {
+				t.Fatalf("expected %q, got %v", test.problem, err)
+			}
+		})
+	}
+}
+
+// TestDocumentedOperationsAreImplemented sends every documented operation to its handler, which
+// answers `not implemented` for methods it does not know.
+func TestDocumentedOperationsAreImplemented(t *testing.T) {
+	useTestDB(t)
+	useAdminKey(t)
+
+	previousStore := ImageStore
+	ImageStore = media.NewLocalBlobStore(t.TempDir())
+	t.Cleanup(func() { ImageStore = previousStore })
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, allFeatures)
+
+	for _, route := range Routes {
+		path := pathVariablePattern.ReplaceAllString(route.Path, "1")
+		for _, operation := range route.Operations {
+			if operation.Method == http.MethodPost && strings.HasPrefix(route.Path, "/admin/") {
+				continue //? the admin posts replace the database, their handlers are covered by the router check
+			}
+
+			request := httptest.NewRequest(operation.Method, path, strings.NewReader("{}"))
+			request.Header.Set("X-API-Key", "adm")
+			request.Header.Set("Content-Type", ContentTypeJSON)
+			recorder := httptest.NewRecorder()
+
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code == http.StatusMethodNotAllowed || recorder.Body.String() == "not implemented\n" {
+				t.Errorf("%s %s is documented but answered %d %q", operation.Method, route.Path, recorder.Code, recorder.Body.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"travelagency/repository"
+)
+
+type searchHandler struct {
+	holidaysRepo *repository.HolidaysRepo
+}
+
+func RespondSearch(writer http.ResponseWriter, request *http.Request) {
+	holidaysRepo, err := repository.NewHolidaysRepo(nil)
+	if err != nil {
+		response := InternalServerError([]byte("couldn't connect to database"))
+
+		writer.WriteHeader(response.Status)
+		writer.Write(response.Content)
+		return
+	}
+
+	holidaysRepo.SetContext(request.Context())
+
+	handler := searchHandler{
+		holidaysRepo: holidaysRepo,
+	}
+
+	writeResponse(writer, handler.respond(request))
+}
+
+func (h *searchHandler) respond(request *http.Request) APIResponse {
+	switch request.Method {
+
+	case http.MethodGet:
+		return h.handleGet(request)
+	default:
+		return InternalServerError([]byte("not implemented\n"))
+	}
+}
+
+func (h *searchHandler) handleGet(request *http.Request) APIResponse {
+	text := request.URL.Query().Get("q")
+	if text == "" {
+		return BadRequestError([]byte("q is empty"))
+	}
+
+	filter, err := parseHolidaysFilter(request)
+	if err != nil {
+		return BadRequestError([]byte(err.Error()))
+	}
+
+	data, err := h.holidaysRepo.Search(text, filter)
+	switch err {
+	case nil:
+	case repository.ErrSearchUnavailable:
+		return NotImplementedError([]byte(err.Error()))
+	case repository.ErrEmptySearchQuery:
+		return BadRequestError([]byte(err.Error()))
+	default:
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	jsonBody, _ := json.Marshal(data)
+	return OKNegotiated(request, jsonBody)
+}
@@ -0,0 +1,17 @@
+//go:build !sqlite_fts5
+
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSearchWithoutFTS5AnswersNotImplemented(t *testing.T) {
+	router := useFixtures(t)
+
+	response := serveRequest(router, http.MethodGet, "/search?q=Lisbon", "", nil)
+	if response.Code != http.StatusNotImplemented {
+		t.Errorf("GET /search without FTS5 answered %d: %s", response.Code, response.Body.String())
+	}
+}
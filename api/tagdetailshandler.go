@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"travelagency/repository"
+
+	"github.com/gorilla/mux"
+)
+
+type tagDetailsHandler struct {
+	tagsRepo *repository.TagsRepo
+}
+
+func RespondTagDetails(writer http.ResponseWriter, request *http.Request) {
+	tagsRepo, err := repository.NewTagsRepo(nil)
+	if err != nil {
+		response := InternalServerError([]byte("couldn't connect to database"))
+
+		writer.WriteHeader(response.Status)
+		writer.Write(response.Content)
+		return
+	}
+
+	actor := actorName(request)
+	tagsRepo.SetActor(actor)
+	tagsRepo.SetContext(request.Context())
+
+	handler := tagDetailsHandler{
+		tagsRepo: tagsRepo,
+	}
+
+	writeResponse(writer, handler.respond(request))
+}
+
+func (h *tagDetailsHandler) respond(request *http.Request) APIResponse {
+	switch request.Method {
+
+	case http.MethodGet:
+		return h.handleGet(request)
+	case http.MethodPut:
+		return h.handlePut(request)
+	case http.MethodDelete:
+		return h.handleDelete(request)
+	default:
+		return InternalServerError([]byte("not implemented\n"))
+	}
+}
+
+func (h *tagDetailsHandler) handleGet(request *http.Request) APIResponse {
+	vars := mux.Vars(request)
+	idStr, exists := vars["id"]
+	if !exists {
+		return BadRequestError([]byte("id is empty"))
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	entity, err := h.tagsRepo.GetByID(id)
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	if entity == nil {
+		return DefaultNotFoundError()
+	}
+
+	jsonBody, _ := json.Marshal(entity)
+	return OKNegotiated(request, jsonBody)
+}
+
+func (h *tagDetailsHandler) handlePut(request *http.Request) APIResponse {
+	vars := mux.Vars(request)
+	idStr, exists := vars["id"]
+	if !exists {
+		return BadRequestError([]byte("id is empty"))
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return BadRequestError([]byte(err.Error()))
+	}
+
+	var body tagHandlerPutBody
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		return decodeErrorResponse(err)
+	}
+
+	//? the id can be left out of the body but must not contradict the path
+	if body.ID != 0 && body.ID != id {
+		return BadRequestError([]byte("id in the body does not match the path"))
+	}
+
+	body.ID = id
+	return putTag(h.tagsRepo, request, body)
+}
+
+func (h *tagDetailsHandler) handleDelete(request *http.Request) APIResponse {
+	vars := mux.Vars(request)
+	idStr, exists := vars["id"]
+	if !exists {
+		return BadRequestError([]byte("id is empty"))
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	err = h.tagsRepo.Delete(id)
+	if err != nil {
+		return repositoryErrorResponse(err)
+	}
+
+	return OKContentType([]byte("true"), ContentTypeJSON)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"travelagency/repository"
+)
+
+type tagsHandler struct {
+	tagsRepo *repository.TagsRepo
+}
+
+type tagHandlerPostBody struct {
+	Name string `json:"name"`
+}
+
+type tagHandlerPutBody struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func RespondTags(writer http.ResponseWriter, request *http.Request) {
+	tagsRepo, err := repository.NewTagsRepo(nil)
+	if err != nil {
+		response := InternalServerError([]byte("couldn't connect to database"))
+
+		writer.WriteHeader(response.Status)
+		writer.Write(response.Content)
+		return
+	}
+
+	actor := actorName(request)
+	tagsRepo.SetActor(actor)
+	tagsRepo.SetContext(request.Context())
+
+	handler := tagsHandler{
+		tagsRepo: tagsRepo,
+	}
+
+	writeResponse(writer, handler.respond(request))
+}
+
+func (h *tagsHandler) respond(request *http.Request) APIResponse {
+	switch request.Method {
+
+	case http.MethodGet:
+		return h.handleGet(request)
+	case http.MethodPost:
+		return h.handlePost(request)
+	case http.MethodPut:
+		return h.handlePut(request)
+	default:
+		return InternalServerError([]byte("not implemented\n"))
+	}
+}
+
+func (h *tagsHandler) handlePost(request *http.Request) APIResponse {
+	var body tagHandlerPostBody
+	err := json.NewDecoder(request.Body).Decode(&body)
+	if err != nil {
+		return decodeErrorResponse(err)
+	}
+
+	name := strings.ToLower(strings.TrimSpace(body.Name))
+	if name == "" {
+		return BadRequestError([]byte("invalid tag name"))
+	}
+
+	entity, err := h.tagsRepo.Insert(repository.TagsEntity{
+		Name: name,
+	})
+
+	if err != nil {
+		return repositoryErrorResponse(err)
+	}
+
+	jsonBody, _ := json.Marshal(entity)
+	return Created(jsonBody, ContentTypeJSON, "/tags/"+strconv.FormatInt(entity.ID, 10))
+}
+
+// handlePut updates the tag with the id in the body, it is kept for old clients and replaced by PUT /tags/{id}.
+func (h *tagsHandler) handlePut(request *http.Request) APIResponse {
+	var body tagHandlerPutBody
+	err := json.NewDecoder(request.Body).Decode(&body)
+	if err != nil {
+		return decodeErrorResponse(err)
+	}
+
+	if body.ID == 0 {
+		return BadRequestError([]byte("invalid tag id"))
+	}
+
+	response := putTag(h.tagsRepo, request, body)
+	return deprecated(response, "/tags/"+strconv.FormatInt(body.ID, 10))
+}
+
+// putTag replaces every field of the tag with the body.
+func putTag(tagsRepo *repository.TagsRepo, request *http.Request, body tagHandlerPutBody) APIResponse {
+	name := strings.ToLower(strings.TrimSpace(body.Name))
+	if name == "" {
+		return BadRequestError([]byte("invalid tag name"))
+	}
+
+	entity, err := tagsRepo.Update(repository.TagsEntity{
+		ID:   body.ID,
+		Name: name,
+	})
+
+	if err != nil {
+		return repositoryErrorResponse(err)
+	}
+
+	jsonBody, _ := json.Marshal(entity)
+	return OKContentType(jsonBody, ContentTypeJSON)
+}
+
+func (h *tagsHandler) handleGet(request *http.Request) APIResponse {
+	data, err := h.tagsRepo.GetAll()
+	if err != nil {
+		return InternalServerError([]byte(err.Error()))
+	}
+
+	jsonBody, _ := json.Marshal(data)
+	return OKNegotiated(request, jsonBody)
+}
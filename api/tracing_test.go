@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"travelagency/tracing"
+
+	"github.com/gorilla/mux"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider that keeps the ended spans in memory.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	if _, err := tracing.Setup(context.Background(), "none"); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	shutdown := tracing.Use(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { shutdown(context.Background()) })
+
+	return recorder
+}
+
+func spanNamed(spans []sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	for _, span := range spans {
+		if span.Name() == name {
+			return span
+		}
+	}
+
+	return nil
+}
+
+func TestTracingNestsRepoAndStatementSpansInTheRequestTrace(t *testing.T) {
+	useTestDB(t)
+	recorder := recordSpans(t)
+
+	router := mux.NewRouter()
+	router.Use(Tracing)
+	RegisterRoutes(router, allFeatures)
+
+	const (
+		traceID      = "4bf92f3577b34da6a3ce929d0e0e4736"
+		remoteSpanID = "00f067aa0ba902b7"
+	)
+
+	request := httptest.NewRequest(http.MethodGet, "/categories", nil)
+	request.Header.Set("traceparent", "00-"+traceID+"-"+remoteSpanID+"-01")
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	if response.Code != http.StatusOK {
+		t.Fatalf("GET /categories answered %d: %s", response.Code, response.Body.String())
+	}
+
+	spans := recorder.Ended()
+
+	server := spanNamed(spans, "GET /categories")
+	if server == nil {
+		t.Fatalf("no server span in %d spans", len(spans))
+	}
+
+	if server.SpanKind() != trace.SpanKindServer {
+		t.Errorf("the request span is a %s span", server.SpanKind())
+	}
+
+	//? the incoming traceparent is continued instead of starting a new trace
+	if server.SpanContext().TraceID().String() != traceID {
+		t.Errorf("the request span is in trace %s, not in %s", server.SpanContext().TraceID(), traceID)
+	}
+
+	if !server.Parent().IsRemote() || server.Parent().SpanID().String() != remoteSpanID {
+		t.Errorf("the request span has the parent %s, not the remote %s", server.Parent().SpanID(), remoteSpanID)
+	}
+
+	repo := spanNamed(spans, "CategoriesRepo.GetAll")
+	if repo == nil {
+		t.Fatal("no repo span")
+	}
+
+	if repo.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Errorf("the repo span is not a child of the request span")
+	}
+
+	statement := spanNamed(spans, "sqlite select")
+	if statement == nil {
+		t.Fatal("no statement span")
+	}
+
+	if statement.Parent().SpanID() != repo.SpanContext().SpanID() {
+		t.Errorf("the statement span is not a child of the repo span")
+	}
+
+	if statement.SpanKind() != trace.SpanKindClient {
+		t.Errorf("the statement span is a %s span", statement.SpanKind())
+	}
+}
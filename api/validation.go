@@ -0,0 +1,63 @@
+package api
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"travelagency/repository"
+)
+
+// validateLocation checks the fields of a location before it is created or patched.
+func validateLocation(entity repository.LocationsEntity) error {
+	if strings.TrimSpace(entity.Street) == "" || strings.TrimSpace(entity.Number) == "" {
+		return errors.New("street and number are required")
+	}
+
+	if strings.TrimSpace(entity.City) == "" || strings.TrimSpace(entity.Country) == "" {
+		return errors.New("city and country are required")
+	}
+
+	return validateCoordinates(entity.Latitude, entity.Longitude)
+}
+
+// validateHoliday checks the fields of a holiday before it is created or patched.
+func validateHoliday(entity repository.HolidaysEntity) error {
+	if strings.TrimSpace(entity.Title) == "" {
+		return errors.New("title is required")
+	}
+
+	if _, err := time.Parse("2006-01-02", entity.StartDate); err != nil {
+		return errors.New("startDate must be formatted as YYYY-MM-DD")
+	}
+
+	if entity.Duration <= 0 {
+		return errors.New("duration must be positive")
+	}
+
+	if entity.Price < 0 {
+		return errors.New("price must not be negative")
+	}
+
+	if entity.FreeSlots < 0 {
+		return errors.New("freeSlots must not be negative")
+	}
+
+	if entity.LocationId <= 0 {
+		return errors.New("location is required")
+	}
+
+	return nil
+}
+
+// validateReservation checks the fields of a reservation before it is created or patched.
+func validateReservation(entity repository.ReservationsEntity) error {
+	if strings.TrimSpace(entity.ContactName) == "" || strings.TrimSpace(entity.PhoneNumber) == "" {
+		return errors.New("contactName and phoneNumber are required")
+	}
+
+	if entity.HolidayId <= 0 {
+		return errors.New("holiday is required")
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"travelagency/api"
+	"travelagency/config"
+	"travelagency/repository"
+)
+
+// backup writes a backup of the database and removes the backups beyond the retention, the server
+// may keep running meanwhile.
+func backup(args []string) error {
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	//? opening a missing database would create an empty one
+	if _, err := os.Stat(cfg.Database.Path); err != nil {
+		return fmt.Errorf("no database to back up: %w", err)
+	}
+
+	repository.Configure(cfg.Database.Path, cfg.Database.ConnectionString())
+	defer repository.CloseDB()
+
+	snapshot, err := repository.BackupDB(cfg.Database.SnapshotDir)
+	if err != nil {
+		return fmt.Errorf("error backing up the database: %w", err)
+	}
+
+	fmt.Printf("backed up to %s ( %d bytes )\n", filepath.Join(cfg.Database.SnapshotDir, snapshot.Name), snapshot.Size)
+
+	removed, err := repository.PruneBackups(cfg.Database.SnapshotDir, cfg.Backup.Retention)
+	for _, name := range removed {
+		fmt.Println("removed", filepath.Join(cfg.Database.SnapshotDir, name))
+	}
+
+	if err != nil {
+		return fmt.Errorf("error removing old backups: %w", err)
+	}
+
+	return nil
+}
+
+// scheduleBackups backs the database up every interval until ctx is done, a failed backup is logged
+// and tried again on the next tick.
+func scheduleBackups(ctx context.Context, dir string, cfg config.BackupConfig) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	api.Logger.Info("scheduled backups", "interval", cfg.Interval.String(), "retention", cfg.Retention)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		snapshot, err := repository.BackupDB(dir)
+		if err != nil {
+			api.Logger.Error("scheduled backup", "error", err.Error())
+			continue
+		}
+
+		removed, err := repository.PruneBackups(dir, cfg.Retention)
+		if err != nil {
+			api.Logger.Error("pruning backups", "error", err.Error())
+		}
+
+		api.Logger.Info("scheduled backup", "snapshot", snapshot.Name, "size", snapshot.Size, "pruned", removed)
+	}
+}
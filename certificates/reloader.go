@@ -0,0 +1,189 @@
+package certificates
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+var errNoCertificates = errors.New("no PEM certificates found")
+
+// Reloader serves the certificate of a cert and key file pair and the client CAs of an optional CA
+// file, Watch loads them again when one of the files changes so rotated certificates are picked up
+// without a restart.
+type Reloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+
+	mu          sync.RWMutex
+	certificate *tls.Certificate
+	clientCAs   *x509.CertPool
+	modTimes    []time.Time
+}
+
+// NewReloader loads the files, clientCAFile may be empty when clients are not authenticated.
+func NewReloader(certFile string, keyFile string, clientCAFile string) (*Reloader, error) {
+	reloader := &Reloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+	}
+
+	if err := reloader.load(); err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+// Config returns the TLS configuration of the server, HTTP/2 is offered when http2 is set and
+// clientAuth decides whether clients have to present a certificate signed by the client CAs.
+func (reloader *Reloader) Config(clientAuth tls.ClientAuthType, http2 bool) *tls.Config {
+	nextProtos := []string{"http/1.1"}
+	if http2 {
+		nextProtos = []string{"h2", "http/1.1"}
+	}
+
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		NextProtos:     nextProtos,
+		ClientAuth:     clientAuth,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	//? the client CAs are read per handshake so that a reloaded CA file applies to new connections
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		reloader.mu.RLock()
+		defer reloader.mu.RUnlock()
+
+		handshake := config.Clone()
+		handshake.GetConfigForClient = nil
+		handshake.ClientCAs = reloader.clientCAs
+		return handshake, nil
+	}
+
+	return config
+}
+
+// GetCertificate returns the certificate loaded last.
+func (reloader *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	reloader.mu.RLock()
+	defer reloader.mu.RUnlock()
+
+	return reloader.certificate, nil
+}
+
+// Watch checks the files every interval until ctx is done, a change that does not load keeps the
+// previous certificate and is logged.
+func (reloader *Reloader) Watch(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changed, err := reloader.changed()
+		if err != nil {
+			logger.Error("checking the TLS files", "error", err.Error())
+			continue
+		}
+
+		if !changed {
+			continue
+		}
+
+		if err := reloader.load(); err != nil {
+			logger.Error("reloading the TLS files, keeping the previous certificate", "error", err.Error())
+			continue
+		}
+
+		logger.Info("reloaded the TLS files", "certFile", reloader.certFile)
+	}
+}
+
+func (reloader *Reloader) files() []string {
+	files := []string{reloader.certFile, reloader.keyFile}
+	if reloader.clientCAFile != "" {
+		files = append(files, reloader.clientCAFile)
+	}
+
+	return files
+}
+
+func (reloader *Reloader) changed() (bool, error) {
+	modTimes, err := modTimesOf(reloader.files())
+	if err != nil {
+		return false, err
+	}
+
+	reloader.mu.RLock()
+	defer reloader.mu.RUnlock()
+
+	for i, modTime := range modTimes {
+		if !modTime.Equal(reloader.modTimes[i]) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// load reads all files before replacing anything, a certificate written halfway fails to parse and
+// is retried on the next tick.
+func (reloader *Reloader) load() error {
+	modTimes, err := modTimesOf(reloader.files())
+	if err != nil {
+		return err
+	}
+
+	certificate, err := tls.LoadX509KeyPair(reloader.certFile, reloader.keyFile)
+	if err != nil {
+		return err
+	}
+
+	var clientCAs *x509.CertPool
+	if reloader.clientCAFile != "" {
+		content, err := os.ReadFile(reloader.clientCAFile)
+		if err != nil {
+			return err
+		}
+
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(content) {
+			return fmt.Errorf("%s: %w", reloader.clientCAFile, errNoCertificates)
+		}
+	}
+
+	reloader.mu.Lock()
+	defer reloader.mu.Unlock()
+
+	reloader.certificate = &certificate
+	reloader.clientCAs = clientCAs
+	reloader.modTimes = modTimes
+	return nil
+}
+
+func modTimesOf(files []string) ([]time.Time, error) {
+	modTimes := make([]time.Time, len(files))
+	for i, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+
+		modTimes[i] = info.ModTime()
+	}
+
+	return modTimes, nil
+}
@@ -0,0 +1,447 @@
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the effective configuration of the server. Every setting has a default, can be set in
+// the YAML file, then overridden by its environment variable and finally by its flag, see Load.
+type Config struct {
+	Environment string            `yaml:"environment" env:"TRAVELAGENCY_ENV" flag:"env" usage:"development or production, production disables POST /admin/reset"`
+	Server      ServerConfig      `yaml:"server"`
+	TLS         TLSConfig         `yaml:"tls"`
+	Database    DatabaseConfig    `yaml:"database"`
+	Backup      BackupConfig      `yaml:"backup"`
+	Auth        AuthConfig        `yaml:"auth"`
+	CORS        CORSConfig        `yaml:"cors"`
+	Limits      LimitsConfig      `yaml:"limits"`
+	RateLimit   RateLimitConfig   `yaml:"rateLimit"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Media       MediaConfig       `yaml:"media"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Features    FeaturesConfig    `yaml:"features"`
+}
+
+type ServerConfig struct {
+	Address           string        `yaml:"address" env:"TRAVELAGENCY_ADDRESS" flag:"address" usage:"host:port to listen on"`
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout" env:"TRAVELAGENCY_READ_HEADER_TIMEOUT" flag:"read-header-timeout" usage:"time to read the request headers"`
+	ReadTimeout       time.Duration `yaml:"readTimeout" env:"TRAVELAGENCY_READ_TIMEOUT" flag:"read-timeout" usage:"time to read the whole request"`
+	WriteTimeout      time.Duration `yaml:"writeTimeout" env:"TRAVELAGENCY_WRITE_TIMEOUT" flag:"write-timeout" usage:"time to write the response, exports have to finish within it"`
+	IdleTimeout       time.Duration `yaml:"idleTimeout" env:"TRAVELAGENCY_IDLE_TIMEOUT" flag:"idle-timeout" usage:"time a keep-alive connection waits for the next request"`
+	ShutdownTimeout   time.Duration `yaml:"shutdownTimeout" env:"TRAVELAGENCY_SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"time running requests get to finish after SIGINT or SIGTERM"`
+}
+
+// TLSConfig serves HTTPS when both files are set, the files are reloaded when they change.
+type TLSConfig struct {
+	CertFile        string        `yaml:"certFile" env:"TRAVELAGENCY_TLS_CERT" flag:"tls-cert" usage:"PEM certificate chain, enables HTTPS with tls-key"`
+	KeyFile         string        `yaml:"keyFile" env:"TRAVELAGENCY_TLS_KEY" flag:"tls-key" usage:"PEM private key of the certificate"`
+	ClientCAFile    string        `yaml:"clientCAFile" env:"TRAVELAGENCY_TLS_CLIENT_CA" flag:"tls-client-ca" usage:"PEM CAs signing the certificates of clients"`
+	ClientAuth      string        `yaml:"clientAuth" env:"TRAVELAGENCY_TLS_CLIENT_AUTH" flag:"tls-client-auth" usage:"none, request ( verified when sent ) or require a client certificate"`
+	ReloadInterval  time.Duration `yaml:"reloadInterval" env:"TRAVELAGENCY_TLS_RELOAD_INTERVAL" flag:"tls-reload-interval" usage:"how often the TLS files are checked for changes"`
+	HTTP2           bool          `yaml:"http2" env:"TRAVELAGENCY_TLS_HTTP2" flag:"tls-http2" usage:"offer HTTP/2 to clients"`
+	RedirectAddress string        `yaml:"redirectAddress" env:"TRAVELAGENCY_TLS_REDIRECT_ADDRESS" flag:"tls-redirect-address" usage:"host:port of a plain HTTP listener redirecting to HTTPS"`
+}
+
+// DatabaseConfig locates the SQLite database, the DSN is built from the path and the pragmas unless
+// it is set explicitly.
+type DatabaseConfig struct {
+	Path        string            `yaml:"path" env:"TRAVELAGENCY_DB_PATH" flag:"db-path" usage:"SQLite database file"`
+	SnapshotDir string            `yaml:"snapshotDir" env:"TRAVELAGENCY_DB_SNAPSHOT_DIR" flag:"db-snapshot-dir" usage:"directory of the backups and of the snapshots taken before a reset or restore"`
+	DSN         string            `yaml:"dsn" env:"TRAVELAGENCY_DB_DSN" flag:"db-dsn" usage:"go-sqlite3 connection string, replaces the one built from db-path and db-pragmas"`
+	Pragmas     map[string]string `yaml:"pragmas" env:"TRAVELAGENCY_DB_PRAGMAS" flag:"db-pragmas" usage:"go-sqlite3 connection options as name=value,name=value"`
+}
+
+// BackupConfig schedules backups into database.snapshotDir.
+type BackupConfig struct {
+	Interval  time.Duration `yaml:"interval" env:"TRAVELAGENCY_BACKUP_INTERVAL" flag:"backup-interval" usage:"time between scheduled backups, 0 turns them off"`
+	Retention int           `yaml:"retention" env:"TRAVELAGENCY_BACKUP_RETENTION" flag:"backup-retention" usage:"number of backups to keep, 0 keeps every backup"`
+}
+
+type AuthConfig struct {
+	APIKeys []string `yaml:"apiKeys" env:"TRAVELAGENCY_API_KEYS" flag:"api-keys" usage:"API keys as key:name:role,key:name:role"`
+}
+
+type CORSConfig struct {
+	AllowedOrigins   []string      `yaml:"allowedOrigins" env:"TRAVELAGENCY_CORS_ORIGINS" flag:"cors-origins" usage:"origins allowed to call the API, * or https://*.example.com match several"`
+	AllowedMethods   []string      `yaml:"allowedMethods" env:"TRAVELAGENCY_CORS_METHODS" flag:"cors-methods" usage:"methods allowed in cross-origin requests"`
+	AllowedHeaders   []string      `yaml:"allowedHeaders" env:"TRAVELAGENCY_CORS_HEADERS" flag:"cors-headers" usage:"request headers allowed in cross-origin requests"`
+	AllowCredentials bool          `yaml:"allowCredentials" env:"TRAVELAGENCY_CORS_CREDENTIALS" flag:"cors-credentials" usage:"allow cookies and authorization headers in cross-origin requests"`
+	MaxAge           time.Duration `yaml:"maxAge" env:"TRAVELAGENCY_CORS_MAX_AGE" flag:"cors-max-age" usage:"how long browsers may cache a preflight response"`
+}
+
+type LimitsConfig struct {
+	MaxBodyBytes     int64  `yaml:"maxBodyBytes" env:"TRAVELAGENCY_MAX_BODY_BYTES" flag:"max-body-bytes" usage:"body limit of the routes without uploads or imports"`
+	MinFreeDiskBytes uint64 `yaml:"minFreeDiskBytes" env:"TRAVELAGENCY_MIN_FREE_DISK_BYTES" flag:"min-free-disk-bytes" usage:"free space next to the database below which /readyz fails"`
+}
+
+// RateLimitConfig budgets the requests of every API key, or client IP without one, separately for
+// reads and writes.
+type RateLimitConfig struct {
+	Enabled         bool `yaml:"enabled" env:"TRAVELAGENCY_RATE_LIMIT" flag:"rate-limit" usage:"limit the requests of every client"`
+	ReadsPerMinute  int  `yaml:"readsPerMinute" env:"TRAVELAGENCY_RATE_LIMIT_READS" flag:"rate-limit-reads" usage:"GET requests a client may make per minute"`
+	ReadBurst       int  `yaml:"readBurst" env:"TRAVELAGENCY_RATE_LIMIT_READ_BURST" flag:"rate-limit-read-burst" usage:"GET requests a client may make at once"`
+	WritesPerMinute int  `yaml:"writesPerMinute" env:"TRAVELAGENCY_RATE_LIMIT_WRITES" flag:"rate-limit-writes" usage:"other requests a client may make per minute"`
+	WriteBurst      int  `yaml:"writeBurst" env:"TRAVELAGENCY_RATE_LIMIT_WRITE_BURST" flag:"rate-limit-write-burst" usage:"other requests a client may make at once"`
+}
+
+type IdempotencyConfig struct {
+	TTL time.Duration `yaml:"ttl" env:"TRAVELAGENCY_IDEMPOTENCY_TTL" flag:"idempotency-ttl" usage:"how long responses to Idempotency-Key requests are kept"`
+}
+
+type MediaConfig struct {
+	Directory string `yaml:"directory" env:"TRAVELAGENCY_MEDIA_DIR" flag:"media-dir" usage:"directory of the uploaded images"`
+}
+
+type TracingConfig struct {
+	Exporter string `yaml:"exporter" env:"OTEL_TRACES_EXPORTER" flag:"traces-exporter" usage:"otlp, console or none"`
+}
+
+// FeaturesConfig switches optional routes off, they stay in the OpenAPI document and answer 404.
+type FeaturesConfig struct {
+	Docs    bool `yaml:"docs" env:"TRAVELAGENCY_FEATURE_DOCS" flag:"feature-docs" usage:"serve /openapi.json and /docs"`
+	Metrics bool `yaml:"metrics" env:"TRAVELAGENCY_FEATURE_METRICS" flag:"feature-metrics" usage:"serve /metrics"`
+	Search  bool `yaml:"search" env:"TRAVELAGENCY_FEATURE_SEARCH" flag:"feature-search" usage:"serve /search"`
+}
+
+const redacted = "REDACTED"
+
+const (
+	EnvironmentDevelopment = "development"
+	EnvironmentProduction  = "production"
+)
+
+const (
+	ClientAuthNone    = "none"
+	ClientAuthRequest = "request"
+	ClientAuthRequire = "require"
+)
+
+var (
+	traceExporters = []string{"", "none", "otlp", "console", "stdout"}
+	clientAuths    = []string{ClientAuthNone, ClientAuthRequest, ClientAuthRequire}
+	environments   = []string{EnvironmentDevelopment, EnvironmentProduction}
+	apiKeyRoles    = []string{"admin", "agent"}
+)
+
+// Default returns the settings used when nothing else is configured.
+func Default() Config {
+	return Config{
+		Environment: EnvironmentDevelopment,
+		Server: ServerConfig{
+			Address:           "127.0.0.1:8080",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      5 * time.Minute,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		TLS: TLSConfig{
+			ClientAuth:     ClientAuthNone,
+			ReloadInterval: 10 * time.Second,
+			HTTP2:          true,
+		},
+		Database: DatabaseConfig{
+			Path:        "sqlite.db",
+			SnapshotDir: "snapshots",
+			Pragmas: map[string]string{
+				"foreign_keys": "1",
+				"busy_timeout": "5000",
+				"txlock":       "immediate",
+			},
+		},
+		Backup: BackupConfig{
+			Retention: 7,
+		},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+			AllowedHeaders: []string{"Content-Type", "X-API-Key", "If-Match", "If-None-Match", "Idempotency-Key", "X-Request-ID"},
+			MaxAge:         10 * time.Minute,
+		},
+		Limits: LimitsConfig{
+			MaxBodyBytes:     1 << 20,
+			MinFreeDiskBytes: 100 << 20,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:         true,
+			ReadsPerMinute:  600,
+			ReadBurst:       100,
+			WritesPerMinute: 60,
+			WriteBurst:      20,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL: 24 * time.Hour,
+		},
+		Media: MediaConfig{
+			Directory: "uploads",
+		},
+		Features: FeaturesConfig{
+			Docs:    true,
+			Metrics: true,
+			Search:  true,
+		},
+	}
+}
+
+// ConnectionString returns the DSN, built from the path and the pragmas as `_name=value` options
+// unless one is configured.
+func (database DatabaseConfig) ConnectionString() string {
+	if database.DSN != "" {
+		return database.DSN
+	}
+
+	names := make([]string, 0, len(database.Pragmas))
+	for name := range database.Pragmas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	options := make([]string, 0, len(names))
+	for _, name := range names {
+		options = append(options, "_"+url.QueryEscape(name)+"="+url.QueryEscape(database.Pragmas[name]))
+	}
+
+	if len(options) == 0 {
+		return "file:" + database.Path
+	}
+
+	return "file:" + database.Path + "?" + strings.Join(options, "&")
+}
+
+// TLSEnabled reports whether the server serves HTTPS.
+func (config Config) TLSEnabled() bool {
+	return config.TLS.CertFile != "" || config.TLS.KeyFile != ""
+}
+
+// ClientAuthType maps ClientAuth to the verification of client certificates.
+func (config TLSConfig) ClientAuthType() tls.ClientAuthType {
+	switch config.ClientAuth {
+	case ClientAuthRequest:
+		return tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		return tls.RequireAndVerifyClientCert
+	default:
+		return tls.NoClientCert
+	}
+}
+
+// Validate returns every setting that can not work, joined into one error.
+func (config Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if !contains(environments, config.Environment) {
+		invalid("environment %q must be one of %s", config.Environment, strings.Join(environments, ", "))
+	}
+
+	if _, _, err := net.SplitHostPort(config.Server.Address); err != nil {
+		invalid("server.address %q: %v", config.Server.Address, err)
+	}
+
+	timeouts := map[string]time.Duration{
+		"server.readHeaderTimeout": config.Server.ReadHeaderTimeout,
+		"server.readTimeout":       config.Server.ReadTimeout,
+		"server.writeTimeout":      config.Server.WriteTimeout,
+		"server.idleTimeout":       config.Server.IdleTimeout,
+		"server.shutdownTimeout":   config.Server.ShutdownTimeout,
+		"cors.maxAge":              config.CORS.MaxAge,
+	}
+	for _, name := range sortedKeys(timeouts) {
+		if timeouts[name] < 0 {
+			invalid("%s must not be negative", name)
+		}
+	}
+
+	if config.TLSEnabled() {
+		if config.TLS.CertFile == "" || config.TLS.KeyFile == "" {
+			invalid("tls.certFile and tls.keyFile must be set together")
+		}
+
+		files := map[string]string{"tls.certFile": config.TLS.CertFile, "tls.keyFile": config.TLS.KeyFile, "tls.clientCAFile": config.TLS.ClientCAFile}
+		for _, name := range sortedKeys(files) {
+			if files[name] == "" {
+				continue
+			}
+
+			if _, err := os.Stat(files[name]); err != nil {
+				invalid("%s: %v", name, err)
+			}
+		}
+	}
+
+	if !contains(clientAuths, config.TLS.ClientAuth) {
+		invalid("tls.clientAuth %q must be one of %s", config.TLS.ClientAuth, strings.Join(clientAuths, ", "))
+	}
+
+	if config.TLS.ClientAuth != ClientAuthNone && config.TLS.ClientCAFile == "" {
+		invalid("tls.clientAuth %s needs tls.clientCAFile", config.TLS.ClientAuth)
+	}
+
+	if !config.TLSEnabled() && (config.TLS.ClientCAFile != "" || config.TLS.RedirectAddress != "") {
+		invalid("tls.clientCAFile and tls.redirectAddress need tls.certFile and tls.keyFile")
+	}
+
+	if config.TLS.RedirectAddress != "" {
+		if _, _, err := net.SplitHostPort(config.TLS.RedirectAddress); err != nil {
+			invalid("tls.redirectAddress %q: %v", config.TLS.RedirectAddress, err)
+		}
+	}
+
+	if config.TLS.ReloadInterval <= 0 {
+		invalid("tls.reloadInterval must be positive")
+	}
+
+	if config.Database.Path == "" {
+		invalid("database.path must be set")
+	}
+
+	if config.Database.SnapshotDir == "" {
+		invalid("database.snapshotDir must be set")
+	}
+
+	//? the path is still used to create, check and remove the file
+	if config.Database.DSN != "" && dsnPath(config.Database.DSN) != config.Database.Path {
+		invalid("database.dsn opens %q, it must open database.path %q", dsnPath(config.Database.DSN), config.Database.Path)
+	}
+
+	if config.Backup.Interval < 0 {
+		invalid("backup.interval must not be negative")
+	}
+
+	if config.Backup.Retention < 0 {
+		invalid("backup.retention must not be negative")
+	}
+
+	for _, apiKey := range config.Auth.APIKeys {
+		parts := strings.Split(apiKey, ":")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+			invalid("auth.apiKeys: an entry is not in the format key:name:role")
+			continue
+		}
+
+		if !contains(apiKeyRoles, parts[2]) {
+			invalid("auth.apiKeys: the role of %s must be one of %s", parts[1], strings.Join(apiKeyRoles, ", "))
+		}
+	}
+
+	for _, origin := range config.CORS.AllowedOrigins {
+		if err := validateOrigin(origin); err != nil {
+			invalid("cors.allowedOrigins: %v", err)
+		}
+	}
+
+	if config.CORS.AllowCredentials && contains(config.CORS.AllowedOrigins, "*") {
+		invalid("cors.allowCredentials can not be used with the origin *")
+	}
+
+	if config.Limits.MaxBodyBytes <= 0 {
+		invalid("limits.maxBodyBytes must be positive")
+	}
+
+	if config.RateLimit.Enabled {
+		budgets := map[string]int{
+			"rateLimit.readsPerMinute":  config.RateLimit.ReadsPerMinute,
+			"rateLimit.readBurst":       config.RateLimit.ReadBurst,
+			"rateLimit.writesPerMinute": config.RateLimit.WritesPerMinute,
+			"rateLimit.writeBurst":      config.RateLimit.WriteBurst,
+		}
+		for _, name := range sortedKeys(budgets) {
+			if budgets[name] <= 0 {
+				invalid("%s must be positive", name)
+			}
+		}
+	}
+
+	if config.Idempotency.TTL <= 0 {
+		invalid("idempotency.ttl must be positive")
+	}
+
+	if config.Media.Directory == "" {
+		invalid("media.directory must be set")
+	}
+
+	if !contains(traceExporters, config.Tracing.Exporter) {
+		invalid("tracing.exporter %q must be otlp, console or none", config.Tracing.Exporter)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateOrigin accepts `*` and origins like `https://example.com` or `https://*.example.com`.
+func validateOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+
+	parsed, err := url.Parse(strings.Replace(origin, "*.", "wildcard.", 1))
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" || parsed.Path != "" || parsed.RawQuery != "" {
+		return fmt.Errorf("%q is not an origin like https://example.com", origin)
+	}
+
+	if strings.Count(origin, "*") > 1 || (strings.Contains(origin, "*") && !strings.Contains(origin, "://*.")) {
+		return fmt.Errorf("%q may only start its host with *.", origin)
+	}
+
+	return nil
+}
+
+// dsnPath returns the file a go-sqlite3 DSN opens, the part before its options.
+func dsnPath(dsn string) string {
+	path, _, _ := strings.Cut(strings.TrimPrefix(dsn, "file:"), "?")
+	return path
+}
+
+// Redacted returns a copy without secrets, API keys keep their name and role.
+func (config Config) Redacted() Config {
+	apiKeys := make([]string, len(config.Auth.APIKeys))
+	for i, apiKey := range config.Auth.APIKeys {
+		apiKeys[i] = redacted
+		if _, principal, found := strings.Cut(apiKey, ":"); found {
+			apiKeys[i] = redacted + ":" + principal
+		}
+	}
+	config.Auth.APIKeys = apiKeys
+
+	return config
+}
+
+// String returns the redacted configuration as YAML.
+func (config Config) String() string {
+	content, err := yaml.Marshal(config.Redacted())
+	if err != nil {
+		return err.Error()
+	}
+
+	return string(content)
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes the YAML into a temporary file and returns its path.
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadWithoutSettingsReturnsTheDefaults(t *testing.T) {
+	t.Setenv(configFileEnv, "")
+
+	config, err := Load(nil)
+	if err != nil {
+		t.Fatalf("the defaults are invalid: %v", err)
+	}
+
+	if !reflect.DeepEqual(config, Default()) {
+		t.Errorf("loaded %+v, expected the defaults", config)
+	}
+
+	if dsn := config.Database.ConnectionString(); dsnPath(dsn) != "sqlite.db" {
+		t.Errorf("the default DSN %q does not open sqlite.db", dsn)
+	}
+}
+
+func TestLoadLayersTheFileTheEnvironmentAndTheFlags(t *testing.T) {
+	path := writeConfigFile(t, `
+server:
+  address: 0.0.0.0:9000
+  readTimeout: 10s
+database:
+  path: travel.db
+features:
+  search: false
+`)
+	t.Setenv(configFileEnv, path)
+	t.Setenv("TRAVELAGENCY_ADDRESS", "0.0.0.0:9001")
+	t.Setenv("TRAVELAGENCY_BACKUP_RETENTION", "3")
+
+	config, err := Load([]string{"-address", "127.0.0.1:9002", "-feature-search=true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Server.ReadTimeout != 10*time.Second || config.Database.Path != "travel.db" {
+		t.Errorf("the file was not applied: %+v", config)
+	}
+
+	if config.Backup.Retention != 3 {
+		t.Errorf("the environment was not applied, the retention is %d", config.Backup.Retention)
+	}
+
+	//? flags override the environment, which overrides the file
+	if config.Server.Address != "127.0.0.1:9002" {
+		t.Errorf("the address is %s, not the one of the flag", config.Server.Address)
+	}
+
+	if !config.Features.Search {
+		t.Error("the flag did not override features.search of the file")
+	}
+
+	//? settings nobody touched keep their defaults
+	if config.Server.WriteTimeout != Default().Server.WriteTimeout {
+		t.Errorf("the write timeout changed to %s", config.Server.WriteTimeout)
+	}
+}
+
+func TestLoadRejectsUnknownKeysOfTheFile(t *testing.T) {
+	t.Setenv(configFileEnv, writeConfigFile(t, "server:\n  adress: 0.0.0.0:9000\n"))
+
+	if _, err := Load(nil); err == nil || !strings.Contains(err.Error(), "adress") {
+		t.Errorf("the misspelled key was accepted: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(config *Config)
+		invalid string
+	}{
+		{"defaults", func(config *Config) {}, ""},
+		{"API keys with known roles", func(config *Config) { config.Auth.APIKeys = []string{"a:alice:admin", "b:bob:agent"} }, ""},
+		{"API key with an unknown role", func(config *Config) { config.Auth.APIKeys = []string{"a:alice:root"} }, "the role of alice"},
+		{"API key without a name", func(config *Config) { config.Auth.APIKeys = []string{"a::admin"} }, "key:name:role"},
+		{"DSN of the path", func(config *Config) { config.Database.DSN = "file:sqlite.db?_foreign_keys=1" }, ""},
+		{"DSN without options", func(config *Config) { config.Database.DSN = "file:sqlite.db" }, ""},
+		{"DSN of a file sharing the prefix of the path", func(config *Config) { config.Database.DSN = "file:sqlite.db2?_foreign_keys=1" }, "database.dsn"},
+		{"DSN of another file", func(config *Config) { config.Database.DSN = "file:other.db" }, "database.dsn"},
+		{"credentials for every origin", func(config *Config) {
+			config.CORS.AllowedOrigins = []string{"*"}
+			config.CORS.AllowCredentials = true
+		}, "cors.allowCredentials"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := Default()
+			test.change(&config)
+
+			err := config.Validate()
+			switch {
+			case test.invalid == "" && err != nil:
+				t.Errorf("rejected: %v", err)
+			case test.invalid != "" && (err == nil || !strings.Contains(err.Error(), test.invalid)):
+				t.Errorf("expected an error about %q, got %v", test.invalid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFileEnv names the YAML file when the -config flag is not given.
+const configFileEnv = "TRAVELAGENCY_CONFIG"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setting is a configurable field with the names it is set by in the environment and on the command line.
+type setting struct {
+	value reflect.Value
+	env   string
+	flag  string
+	usage string
+}
+
+// flagValue keeps the raw flag so that it is applied after the file and the environment.
+type flagValue struct {
+	isBool bool
+	raw    string
+}
+
+func (value *flagValue) String() string {
+	return value.raw
+}
+
+func (value *flagValue) Set(raw string) error {
+	value.raw = raw
+	return nil
+}
+
+func (value *flagValue) IsBoolFlag() bool {
+	return value.isBool
+}
+
+// Load layers the defaults, the YAML file of -config or TRAVELAGENCY_CONFIG, the environment and
+// the flags in args, each overriding the ones before, and validates the result.
+func Load(args []string) (Config, error) {
+	config := Default()
+	settings := collectSettings(reflect.ValueOf(&config).Elem())
+
+	flags := flag.NewFlagSet("travelagency", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv(configFileEnv), "YAML configuration file, also read from "+configFileEnv)
+	flagValues := make([]*flagValue, len(settings))
+	for i, setting := range settings {
+		flagValues[i] = &flagValue{isBool: setting.value.Kind() == reflect.Bool}
+		flags.Var(flagValues[i], setting.flag, fmt.Sprintf("%s ( %s )", setting.usage, setting.env))
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return config, err
+	}
+
+	if *configFile != "" {
+		if err := loadFile(*configFile, &config); err != nil {
+			return config, err
+		}
+	}
+
+	for _, setting := range settings {
+		raw, exists := os.LookupEnv(setting.env)
+		if !exists {
+			continue
+		}
+
+		if err := setValue(setting.value, raw); err != nil {
+			return config, fmt.Errorf("%s: %w", setting.env, err)
+		}
+	}
+
+	visited := map[string]bool{}
+	flags.Visit(func(visitedFlag *flag.Flag) {
+		visited[visitedFlag.Name] = true
+	})
+
+	for i, setting := range settings {
+		if !visited[setting.flag] {
+			continue
+		}
+
+		if err := setValue(setting.value, flagValues[i].raw); err != nil {
+			return config, fmt.Errorf("-%s: %w", setting.flag, err)
+		}
+	}
+
+	return config, config.Validate()
+}
+
+// loadFile decodes the YAML file over the defaults, unknown keys are rejected to catch typos.
+func loadFile(path string, config *Config) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+// collectSettings returns the fields with an env tag, nested structs are walked.
+func collectSettings(value reflect.Value) []setting {
+	var settings []setting
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Type.Kind() == reflect.Struct && field.Type != durationType {
+			settings = append(settings, collectSettings(value.Field(i))...)
+			continue
+		}
+
+		env := field.Tag.Get("env")
+		if env == "" {
+			continue
+		}
+
+		settings = append(settings, setting{
+			value: value.Field(i),
+			env:   env,
+			flag:  field.Tag.Get("flag"),
+			usage: field.Tag.Get("usage"),
+		})
+	}
+
+	return settings
+}
+
+// setValue parses raw into the field, lists are comma separated and replace the list, maps are
+// name=value lists added to the map like the entries of the YAML file.
+func setValue(value reflect.Value, raw string) error {
+	if value.Type() == durationType {
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+
+		value.SetInt(int64(duration))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+
+		value.SetBool(parsed)
+	case reflect.Int, reflect.Int64:
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		value.SetInt(parsed)
+	case reflect.Uint64:
+		parsed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		value.SetUint(parsed)
+	case reflect.Slice:
+		value.Set(reflect.ValueOf(splitList(raw)))
+	case reflect.Map:
+		entries := map[string]string{}
+		for iter := value.MapRange(); iter.Next(); {
+			entries[iter.Key().String()] = iter.Value().String()
+		}
+
+		for _, entry := range splitList(raw) {
+			name, mapValue, found := strings.Cut(entry, "=")
+			if !found {
+				return fmt.Errorf("%q is not in the format name=value", entry)
+			}
+
+			entries[strings.TrimSpace(name)] = strings.TrimSpace(mapValue)
+		}
+
+		value.Set(reflect.ValueOf(entries))
+	default:
+		return fmt.Errorf("unsupported setting type %s", value.Type())
+	}
+
+	return nil
+}
+
+func splitList(raw string) []string {
+	values := []string{}
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
@@ -1,61 +1,239 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 	"travelagency/api"
+	"travelagency/certificates"
+	"travelagency/config"
+	"travelagency/media"
+	"travelagency/metrics"
+	"travelagency/ratelimit"
 	"travelagency/repository"
+	"travelagency/tracing"
 
 	"github.com/common-nighthawk/go-figure"
 	"github.com/gorilla/mux"
 )
 
 func main() {
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	var err error
+	switch command {
+	case "serve":
+		err = run(args)
+	case "backup":
+		err = backup(args)
+	default:
+		err = fmt.Errorf("unknown command %q, use serve or backup", command)
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// run serves until SIGINT or SIGTERM and returns the error that kept the server from starting.
+func run(args []string) error {
 	bannerFigure := figure.NewFigure("Travel Agency API", "", true)
 	bannerFigure.Print()
 
-	err := repository.EnsureDBExists()
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+
 	if err != nil {
-		fmt.Println("error initializing database")
-		return
+		return fmt.Errorf("invalid configuration:\n%w", err)
 	}
 
+	fmt.Println("effective configuration:")
+	fmt.Print(cfg)
+	configure(cfg)
+
+	err = repository.EnsureDBExists()
+	if err != nil {
+		return fmt.Errorf("error initializing database: %w", err)
+	}
+	defer repository.CloseDB()
+
+	//? a build without the sqlite_fts5 tag still starts, its /search answers 501
+	if cfg.Features.Search && !repository.SearchAvailable() {
+		api.Logger.Warn("features.search is enabled but SQLite has no FTS5, /search answers 501, build with -tags sqlite_fts5 to enable it")
+	}
+
+	bookingCollector, err := repository.NewBookingCollector(nil)
+	if err != nil {
+		return fmt.Errorf("error initializing metrics: %w", err)
+	}
+	metrics.Registry.MustRegister(bookingCollector)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Exporter)
+	if err != nil {
+		return fmt.Errorf("error initializing tracing: %w", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	router := mux.NewRouter().StrictSlash(true)
+	router.Use(api.RequestID, api.Recover, api.Tracing, api.AccessLog, api.Metrics, api.CORS, api.RateLimit, api.Maintenance, api.LimitBody, api.Idempotency)
+	router.NotFoundHandler = api.RequestID(api.Tracing(api.AccessLog(api.Metrics(http.NotFoundHandler()))))
 
-	router.HandleFunc("/health", getHealth())
-	router.HandleFunc("/restart", getRestartDB())
+	router.MethodNotAllowedHandler = api.RequestID(api.Tracing(api.AccessLog(api.Metrics(http.HandlerFunc(api.RespondMethodNotAllowed)))))
+	api.RegisterRoutes(router, map[string]bool{
+		api.FeatureDocs:    cfg.Features.Docs,
+		api.FeatureMetrics: cfg.Features.Metrics,
+		api.FeatureSearch:  cfg.Features.Search,
+	})
 
-	router.HandleFunc("/locations", api.RespondLocations)
-	router.HandleFunc("/locations/{id}", api.RespondLocationDetails)
+	//? refuse to start when a route was registered on the router without documenting it in api.Routes
+	if err := api.CheckRoutes(router); err != nil {
+		return fmt.Errorf("the OpenAPI routes and the router disagree:\n%w", err)
+	}
 
-	router.HandleFunc("/holidays", api.RespondHolidays)
-	router.HandleFunc("/holidays/{id}", api.RespondHolidayDetails)
+	server := &http.Server{
+		Addr:              cfg.Server.Address,
+		Handler:           router,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
 
-	router.HandleFunc("/reservations", api.RespondReservations)
-	router.HandleFunc("/reservations/{id}", api.RespondReservationDetails)
+	//? deferred before stop so that the workers see the cancellation before they are waited for
+	var workers sync.WaitGroup
+	defer workers.Wait()
 
-	server := &http.Server{Addr: "127.0.0.1:8080", Handler: router}
-	server.ListenAndServe()
-}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-func getHealth() func(writer http.ResponseWriter, request *http.Request) {
-	return func(writer http.ResponseWriter, request *http.Request) {
-		statusCode := http.StatusOK
-		writer.WriteHeader(statusCode)
-		writer.Write([]byte("{status:UP}"))
+	if cfg.Backup.Interval > 0 {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			scheduleBackups(ctx, cfg.Database.SnapshotDir, cfg.Backup)
+		}()
 	}
-}
 
-func getRestartDB() func(writer http.ResponseWriter, request *http.Request) {
-	return func(writer http.ResponseWriter, request *http.Request) {
-		err := repository.RestartDB()
+	if cfg.TLSEnabled() {
+		reloader, err := certificates.NewReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.ClientCAFile)
 		if err != nil {
-			writer.WriteHeader(http.StatusInternalServerError)
-			writer.Write([]byte(err.Error()))
+			return fmt.Errorf("error loading the TLS files: %w", err)
+		}
+
+		server.TLSConfig = reloader.Config(cfg.TLS.ClientAuthType(), cfg.TLS.HTTP2)
+		if !cfg.TLS.HTTP2 {
+			//? an empty map keeps net/http from setting up HTTP/2 by itself
+			server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+		}
+
+		go reloader.Watch(ctx, cfg.TLS.ReloadInterval, api.Logger)
+	}
+
+	listener, err := net.Listen("tcp", cfg.Server.Address)
+	if err != nil {
+		return fmt.Errorf("error listening: %w", err)
+	}
+
+	servers := []*http.Server{server}
+	served := make(chan error, 2)
+	go func() {
+		if cfg.TLSEnabled() {
+			//? the certificate comes from the reloader in server.TLSConfig
+			served <- server.ServeTLS(listener, "", "")
 			return
 		}
 
-		writer.WriteHeader(http.StatusOK)
-		writer.Write([]byte("sucess"))
+		served <- server.Serve(listener)
+	}()
+
+	api.Logger.Info("listening", "address", cfg.Server.Address, "tls", cfg.TLSEnabled())
+
+	if cfg.TLS.RedirectAddress != "" {
+		redirectListener, err := net.Listen("tcp", cfg.TLS.RedirectAddress)
+		if err != nil {
+			return fmt.Errorf("error listening for redirects: %w", err)
+		}
+
+		redirectServer := &http.Server{
+			Handler:           api.RequestID(api.AccessLog(api.RedirectToHTTPS(cfg.Server.Address))),
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+			ReadTimeout:       cfg.Server.ReadTimeout,
+			WriteTimeout:      cfg.Server.WriteTimeout,
+			IdleTimeout:       cfg.Server.IdleTimeout,
+		}
+		servers = append(servers, redirectServer)
+
+		go func() {
+			served <- redirectServer.Serve(redirectListener)
+		}()
+
+		api.Logger.Info("redirecting to HTTPS", "address", cfg.TLS.RedirectAddress)
+	}
+
+	select {
+	case err := <-served:
+		return fmt.Errorf("error serving: %w", err)
+	case <-ctx.Done():
+	}
+
+	//? stop accepting connections and let the running requests finish, a second signal kills the process
+	stop()
+	api.Logger.Info("shutting down", "timeout", cfg.Server.ShutdownTimeout.String())
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	for _, server := range servers {
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			api.Logger.Error("requests did not finish in time, closing their connections", "error", err.Error())
+			server.Close()
+		}
+	}
+
+	api.Logger.Info("stopped")
+	return nil
+}
+
+// configure hands the settings to the packages that use them.
+func configure(cfg config.Config) {
+	repository.Configure(cfg.Database.Path, cfg.Database.ConnectionString())
+
+	api.APIKeys = api.ParseAPIKeys(strings.Join(cfg.Auth.APIKeys, ","))
+	api.IdempotencyKeyTTL = cfg.Idempotency.TTL
+	api.MaxBodyBytes = cfg.Limits.MaxBodyBytes
+	api.MinFreeDiskBytes = cfg.Limits.MinFreeDiskBytes
+	api.ImageStore = media.NewLocalBlobStore(cfg.Media.Directory)
+	api.ResetEnabled = cfg.Environment != config.EnvironmentProduction
+	api.SnapshotDir = cfg.Database.SnapshotDir
+	api.BackupRetention = cfg.Backup.Retention
+	api.CORSPolicy = api.CrossOriginPolicy{
+		AllowedOrigins:   cfg.CORS.AllowedOrigins,
+		AllowedMethods:   cfg.CORS.AllowedMethods,
+		AllowedHeaders:   cfg.CORS.AllowedHeaders,
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           cfg.CORS.MaxAge,
+	}
+
+	if cfg.RateLimit.Enabled {
+		api.RateLimits = api.RateLimitPolicy{
+			Store: ratelimit.NewMemoryStore(),
+			Read:  ratelimit.PerMinute(cfg.RateLimit.ReadsPerMinute, cfg.RateLimit.ReadBurst),
+			Write: ratelimit.PerMinute(cfg.RateLimit.WritesPerMinute, cfg.RateLimit.WriteBurst),
+		}
 	}
 }
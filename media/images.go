@@ -0,0 +1,129 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+
+	_ "image/gif"
+	_ "image/png"
+)
+
+const (
+	MaxImageBytes  = 10 << 20
+	maxImagePixels = 40_000_000
+	thumbnailSize  = 320
+)
+
+var (
+	ErrImageTooLarge       = fmt.Errorf("image is larger than %d bytes", MaxImageBytes)
+	ErrUnsupportedImage    = errors.New("unsupported image type, use jpeg, png or gif")
+	ErrImageTooManyPixels  = errors.New("image dimensions are too large")
+	allowedImageExtensions = map[string]string{
+		"image/jpeg": ".jpg",
+		"image/png":  ".png",
+		"image/gif":  ".gif",
+	}
+)
+
+type Image struct {
+	ContentType string
+	Extension   string
+	Width       int
+	Height      int
+	Content     []byte
+	Thumbnail   []byte //? always a jpeg
+}
+
+// ProcessImage sniffs the content type of an upload, validates it and renders its thumbnail.
+// The declared content type of the upload is ignored.
+func ProcessImage(content []byte) (*Image, error) {
+	if len(content) > MaxImageBytes {
+		return nil, ErrImageTooLarge
+	}
+
+	contentType := http.DetectContentType(content)
+	extension, allowed := allowedImageExtensions[contentType]
+	if !allowed {
+		return nil, ErrUnsupportedImage
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	//? check the dimensions before decoding so a small file cannot allocate a huge bitmap
+	if config.Width*config.Height > maxImagePixels {
+		return nil, ErrImageTooManyPixels
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	thumbnail := bytes.Buffer{}
+	if err := jpeg.Encode(&thumbnail, resize(decoded, thumbnailSize), &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+
+	return &Image{
+		ContentType: contentType,
+		Extension:   extension,
+		Width:       config.Width,
+		Height:      config.Height,
+		Content:     content,
+		Thumbnail:   thumbnail.Bytes(),
+	}, nil
+}
+
+// resize scales the image to fit in a maxSize square keeping its aspect ratio.
+// Every destination pixel is the average of the source pixels it covers.
+func resize(src image.Image, maxSize int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxSize && height <= maxSize {
+		maxSize = max(width, height)
+	}
+
+	dstWidth, dstHeight := maxSize, maxSize
+	if width > height {
+		dstHeight = max(1, height*maxSize/width)
+	} else {
+		dstWidth = max(1, width*maxSize/height)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		srcMinY := bounds.Min.Y + y*height/dstHeight
+		srcMaxY := max(srcMinY+1, bounds.Min.Y+(y+1)*height/dstHeight)
+
+		for x := 0; x < dstWidth; x++ {
+			srcMinX := bounds.Min.X + x*width/dstWidth
+			srcMaxX := max(srcMinX+1, bounds.Min.X+(x+1)*width/dstWidth)
+
+			var r, g, b, a, count uint64
+			for sy := srcMinY; sy < srcMaxY; sy++ {
+				for sx := srcMinX; sx < srcMaxX; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					count++
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / count),
+				G: uint16(g / count),
+				B: uint16(b / count),
+				A: uint16(a / count),
+			})
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,90 @@
+package media
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// LocalBlobStore stores blobs as files under a root directory.
+type LocalBlobStore struct {
+	root string
+}
+
+func NewLocalBlobStore(root string) *LocalBlobStore {
+	return &LocalBlobStore{
+		root: root,
+	}
+}
+
+// filePath maps a key to a file inside the root and rejects keys that would escape it.
+func (store *LocalBlobStore) filePath(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if key == "" || cleaned == "/" || cleaned[1:] != key || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(store.root, filepath.FromSlash(key)), nil
+}
+
+func (store *LocalBlobStore) Put(key string, content io.Reader) error {
+	filePath, err := store.filePath(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return err
+	}
+
+	//? write to a temporary file first so readers never see a partial blob
+	file, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), filePath)
+}
+
+func (store *LocalBlobStore) Open(key string) (io.ReadCloser, error) {
+	filePath, err := store.filePath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+
+	return file, err
+}
+
+func (store *LocalBlobStore) Delete(key string) error {
+	filePath, err := store.filePath(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
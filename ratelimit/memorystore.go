@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often MemoryStore drops the buckets that refilled completely.
+const pruneInterval = time.Minute
+
+// MemoryStore keeps the buckets in the memory of the process.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+	pruned  time.Time
+}
+
+type memoryBucket struct {
+	Bucket
+	full time.Time //? a bucket full again is the same as a new one and can be dropped
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: map[string]*memoryBucket{},
+	}
+}
+
+func (store *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.prune(now)
+
+	bucket, exists := store.buckets[key]
+	if !exists {
+		bucket = &memoryBucket{Bucket: NewBucket(limit, now)}
+		store.buckets[key] = bucket
+	}
+
+	result := bucket.Take(limit, now)
+	bucket.full = now.Add(result.Reset)
+	return result, nil
+}
+
+func (store *MemoryStore) prune(now time.Time) {
+	if now.Sub(store.pruned) < pruneInterval {
+		return
+	}
+
+	for key, bucket := range store.buckets {
+		if !now.Before(bucket.full) {
+			delete(store.buckets, key)
+		}
+	}
+
+	store.pruned = now
+}
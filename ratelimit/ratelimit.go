@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit is a token bucket: Burst requests at once, refilled with Rate tokens per second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute returns the limit of requests spread over a minute with bursts of burst requests.
+func PerMinute(requests int, burst int) Limit {
+	return Limit{Rate: float64(requests) / 60, Burst: burst}
+}
+
+// Result is the state of a bucket after a request took from it.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration //? until the next token when the request was not allowed
+	Reset      time.Duration //? until the bucket is full again
+}
+
+// Store keeps the buckets of the clients. MemoryStore serves a single process, a store shared by
+// several processes can keep Buckets under the same keys.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// Bucket is the stored state of a token bucket.
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// NewBucket returns the full bucket of a client seen for the first time.
+func NewBucket(limit Limit, now time.Time) Bucket {
+	return Bucket{Tokens: float64(limit.Burst), Updated: now}
+}
+
+// Take refills the bucket for the time since it was updated and takes a token if one is left.
+func (bucket *Bucket) Take(limit Limit, now time.Time) Result {
+	if elapsed := now.Sub(bucket.Updated).Seconds(); elapsed > 0 {
+		bucket.Tokens = math.Min(float64(limit.Burst), bucket.Tokens+elapsed*limit.Rate)
+		bucket.Updated = now
+	}
+
+	result := Result{Limit: limit.Burst}
+	if bucket.Tokens >= 1 {
+		bucket.Tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsDuration((1 - bucket.Tokens) / limit.Rate)
+	}
+
+	result.Remaining = int(bucket.Tokens)
+	result.Reset = secondsDuration((float64(limit.Burst) - bucket.Tokens) / limit.Rate)
+	return result
+}
+
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBucketTake(t *testing.T) {
+	start := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	limit := PerMinute(60, 3) //? a token per second, three at once
+
+	tests := []struct {
+		name      string
+		takes     []time.Duration //? offsets from start of the earlier requests
+		at        time.Duration
+		allowed   bool
+		remaining int
+		retry     time.Duration
+	}{
+		{name: "new client", at: 0, allowed: true, remaining: 2},
+		{name: "burst used up", takes: []time.Duration{0, 0, 0}, at: 0, allowed: false, remaining: 0, retry: time.Second},
+		{name: "half a token refilled", takes: []time.Duration{0, 0, 0}, at: 500 * time.Millisecond, allowed: false, remaining: 0, retry: 500 * time.Millisecond},
+		{name: "a token refilled", takes: []time.Duration{0, 0, 0}, at: time.Second, allowed: true, remaining: 0},
+		{name: "two tokens refilled", takes: []time.Duration{0, 0, 0}, at: 2 * time.Second, allowed: true, remaining: 1},
+		{name: "refill capped at the burst", takes: []time.Duration{0}, at: time.Hour, allowed: true, remaining: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bucket := NewBucket(limit, start)
+			for _, offset := range test.takes {
+				bucket.Take(limit, start.Add(offset))
+			}
+
+			result := bucket.Take(limit, start.Add(test.at))
+			if result.Allowed != test.allowed || result.Remaining != test.remaining || result.Limit != 3 {
+				t.Errorf("got %+v, expected allowed %t with %d remaining", result, test.allowed, test.remaining)
+			}
+
+			if !test.allowed && result.RetryAfter != test.retry {
+				t.Errorf("retry after %s, expected %s", result.RetryAfter, test.retry)
+			}
+
+			if bucket.Tokens > float64(limit.Burst) {
+				t.Errorf("the bucket holds %f tokens, more than the burst", bucket.Tokens)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreKeepsABucketPerKey(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	limit := PerMinute(60, 1)
+
+	take := func(key string, at time.Time) bool {
+		t.Helper()
+
+		result, err := store.Take(context.Background(), key, limit, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return result.Allowed
+	}
+
+	if !take("read|ip:192.0.2.1", now) || take("read|ip:192.0.2.1", now) {
+		t.Fatal("the bucket of a single request was not used up")
+	}
+
+	for _, key := range []string{"write|ip:192.0.2.1", "read|ip:192.0.2.2"} {
+		if !take(key, now) {
+			t.Errorf("%s shares the bucket of read|ip:192.0.2.1", key)
+		}
+	}
+
+	//? buckets that refilled completely are dropped and start full
+	later := now.Add(pruneInterval)
+	if !take("read|ip:192.0.2.1", later) {
+		t.Error("the bucket did not refill")
+	}
+
+	if len(store.buckets) != 1 {
+		t.Errorf("%d buckets are kept after pruning, expected the one just used", len(store.buckets))
+	}
+}
@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+const (
+	AuditEntityLocation      = "location"
+	AuditEntityLocationImage = "locationImage"
+	AuditEntityCategory      = "category"
+	AuditEntityTag           = "tag"
+	AuditEntityHoliday       = "holiday"
+	AuditEntityReservation   = "reservation"
+	AuditEntityReview        = "review"
+
+	AuditActionInsert  = "insert"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+
+	//? recorded when a repository is used without an actor, e.g. while creating the db
+	systemActor = "system"
+)
+
+var auditTables = map[string]string{
+	AuditEntityLocation:      "locations",
+	AuditEntityLocationImage: "location_images",
+	AuditEntityCategory:      "categories",
+	AuditEntityTag:           "tags",
+	AuditEntityHoliday:       "holidays",
+	AuditEntityReservation:   "reservations",
+	AuditEntityReview:        "reviews",
+}
+
+type AuditEntity struct {
+	ID         int64           `json:"id"`
+	Actor      string          `json:"actor"`
+	CreatedAt  string          `json:"createdAt"`
+	EntityType string          `json:"entity"`
+	EntityId   int64           `json:"entityId"`
+	Action     string          `json:"action"`
+	Diff       json.RawMessage `json:"diff"`
+}
+
+type AuditFilter struct {
+	EntityType string
+	EntityId   int64
+	Actor      string
+}
+
+// AuditChange is the value of one column before and after a mutation.
+type AuditChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+func IsAuditEntity(entityType string) bool {
+	_, exists := auditTables[entityType]
+	return exists
+}
+
+// snapshotRow reads every column of a row, it returns nil when the row does not exist.
+func snapshotRow(db execQuerier, entityType string, id int64) (map[string]any, error) {
+	rows, err := db.Query("SELECT * FROM "+auditTables[entityType]+" WHERE id = ?;", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, err
+	}
+
+	snapshot := map[string]any{}
+	for i, column := range columns {
+		if bytes, ok := values[i].([]byte); ok {
+			values[i] = string(bytes)
+		}
+
+		snapshot[column] = values[i]
+	}
+	rows.Close()
+
+	//? tags live in their own table but are part of the holiday
+	if entityType == AuditEntityHoliday {
+		tagIds, err := holidayTagIds(db, id)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot["tagIds"] = tagIds
+	}
+
+	return snapshot, nil
+}
+
+func holidayTagIds(db execQuerier, holidayID int64) ([]int64, error) {
+	rows, err := db.Query("SELECT tagId FROM holiday_tags WHERE holidayId = ? ORDER BY tagId;", holidayID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// recordAudit compares the row with its snapshot from before the mutation and appends the changes to the audit log.
+// It must run in the transaction of the mutation so that both are committed or rolled back together.
+func recordAudit(tx execQuerier, actor string, entityType string, id int64, before map[string]any) error {
+	return recordAuditAction(tx, actor, entityType, id, before, "")
+}
+
+// recordAuditAction is recordAudit with an explicit action, e.g. for soft deletes that only update the row.
+// The action is derived from the snapshots when it is empty.
+func recordAuditAction(tx execQuerier, actor string, entityType string, id int64, before map[string]any, action string) error {
+	after, err := snapshotRow(tx, entityType, id)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case before == nil && after == nil:
+		return nil
+	case action != "":
+	case before == nil:
+		action = AuditActionInsert
+	case after == nil:
+		action = AuditActionDelete
+	default:
+		action = AuditActionUpdate
+	}
+
+	diff := map[string]AuditChange{}
+	for column, value := range after {
+		if !reflect.DeepEqual(before[column], value) {
+			diff[column] = AuditChange{Old: before[column], New: value}
+		}
+	}
+
+	for column, value := range before {
+		if _, exists := after[column]; !exists {
+			diff[column] = AuditChange{Old: value, New: nil}
+		}
+	}
+
+	//? nothing changed, e.g. a PUT with the current values
+	if len(diff) == 0 {
+		return nil
+	}
+
+	diffJSON, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+
+	if actor == "" {
+		actor = systemActor
+	}
+
+	_, err = tx.Exec("INSERT INTO audit_log (actor, createdAt, entityType, entityId, action, diff) VALUES(?,?,?,?,?,?);", actor, time.Now().UTC().Format(time.RFC3339Nano), entityType, id, action, string(diffJSON))
+	return err
+}
+
+// SetContext sets the context the statements of the repo are traced in.
+func (aud *AuditRepo) SetContext(ctx context.Context) {
+	aud.db.setContext(ctx)
+}
+
+func (aud *AuditRepo) traceContext() context.Context {
+	return aud.db.ctx
+}
+
+func (aud *AuditRepo) GetAll(filter AuditFilter) ([]AuditEntity, error) {
+	defer startSpan(aud, "AuditRepo.GetAll")()
+
+	query := "SELECT id, actor, createdAt, entityType, entityId, action, diff FROM audit_log WHERE 1=1"
+	args := []interface{}{}
+
+	if filter.EntityType != "" {
+		query += " AND entityType = ? "
+		args = append(args, filter.EntityType)
+	}
+
+	if filter.EntityId != 0 {
+		query += " AND entityId = ? "
+		args = append(args, filter.EntityId)
+	}
+
+	if filter.Actor != "" {
+		query += " AND actor = ? "
+		args = append(args, filter.Actor)
+	}
+
+	query += " ORDER BY id;"
+
+	rows, err := aud.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []AuditEntity{}
+	for rows.Next() {
+		entity := AuditEntity{}
+		var diff string
+		err = rows.Scan(&entity.ID, &entity.Actor, &entity.CreatedAt, &entity.EntityType, &entity.EntityId, &entity.Action, &diff)
+		if err != nil {
+			return nil, err
+		}
+
+		entity.Diff = json.RawMessage(diff)
+		data = append(data, entity)
+	}
+
+	return data, nil
+}
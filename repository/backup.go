@@ -0,0 +1,277 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	backupPrefix     = "backup-"
+	preRestorePrefix = "pre-restore-"
+	resetPrefix      = "reset-"
+	snapshotSuffix   = ".db"
+)
+
+var (
+	ErrSnapshotNotFound    = errors.New("snapshot not found")
+	ErrInvalidSnapshotName = errors.New("invalid snapshot name")
+	ErrInvalidSnapshot     = errors.New("the snapshot is not a usable database")
+)
+
+// snapshotMu keeps backups, restores and resets from replacing the database under each other.
+var snapshotMu sync.Mutex
+
+// snapshotNamePattern keeps snapshot names to plain file names inside the snapshot directory.
+var snapshotNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*\.db$`)
+
+type Snapshot struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// snapshotFileName names a snapshot after its kind and the current time, milliseconds keep two
+// backups in the same second apart.
+func snapshotFileName(prefix string) string {
+	return prefix + time.Now().UTC().Format("20060102T150405.000Z") + snapshotSuffix
+}
+
+// BackupDB writes a consistent copy of the running database into dir.
+func BackupDB(dir string) (Snapshot, error) {
+	snapshotMu.Lock()
+	defer snapshotMu.Unlock()
+
+	file := filepath.Join(dir, snapshotFileName(backupPrefix))
+	if err := SnapshotDB(file); err != nil {
+		return Snapshot{}, err
+	}
+
+	return snapshotOf(file)
+}
+
+// ListSnapshots returns the backups and the snapshots taken before resets and restores, newest first.
+func ListSnapshots(dir string) ([]Snapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Snapshot{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []Snapshot{}
+	for _, entry := range entries {
+		if entry.IsDir() || !snapshotNamePattern.MatchString(entry.Name()) {
+			continue
+		}
+
+		snapshot, err := snapshotOf(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+
+	return snapshots, nil
+}
+
+// PruneBackups removes all but the newest keep backups, snapshots taken before resets and restores
+// are left alone. A keep of 0 keeps every backup.
+func PruneBackups(dir string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+
+	snapshots, err := ListSnapshots(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := []string{}
+	kept := 0
+	for _, snapshot := range snapshots {
+		if !strings.HasPrefix(snapshot.Name, backupPrefix) {
+			continue
+		}
+
+		if kept < keep {
+			kept++
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(dir, snapshot.Name)); err != nil {
+			return removed, err
+		}
+
+		removed = append(removed, snapshot.Name)
+	}
+
+	return removed, nil
+}
+
+// RestoreDB replaces the database with the snapshot name from dir and migrates it to SchemaVersion.
+// The current database is kept as a pre-restore snapshot first, which is returned. The caller has to
+// hold the requests back, the shared pool is closed under them.
+func RestoreDB(dir string, name string) (Snapshot, error) {
+	if !snapshotNamePattern.MatchString(name) {
+		return Snapshot{}, ErrInvalidSnapshotName
+	}
+
+	snapshotMu.Lock()
+	defer snapshotMu.Unlock()
+
+	source := filepath.Join(dir, name)
+	if _, err := os.Stat(source); errors.Is(err, os.ErrNotExist) {
+		return Snapshot{}, ErrSnapshotNotFound
+	}
+
+	if err := checkSnapshot(source); err != nil {
+		return Snapshot{}, err
+	}
+
+	preRestore := filepath.Join(dir, snapshotFileName(preRestorePrefix))
+	if err := SnapshotDB(preRestore); err != nil {
+		return Snapshot{}, err
+	}
+
+	kept, err := snapshotOf(preRestore)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	//? the connections must not keep using the replaced file
+	if err := CloseDB(); err != nil {
+		return kept, err
+	}
+
+	if err := replaceFile(source, databaseFile); err != nil {
+		return kept, err
+	}
+
+	return kept, MigrateDB()
+}
+
+// checkSnapshot checks that the snapshot is intact and not from a newer version of the server.
+func checkSnapshot(file string) error {
+	db, err := sql.Open(driverName, "file:"+file+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tables, err := storedTables(db)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+
+	for _, table := range tables {
+		var result string
+		if err := db.QueryRow(`PRAGMA quick_check("` + strings.ReplaceAll(table, `"`, `""`) + `");`).Scan(&result); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+		}
+
+		if result != "ok" {
+			return fmt.Errorf("%w: %s", ErrInvalidSnapshot, result)
+		}
+	}
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version;").Scan(&version); err != nil {
+		return err
+	}
+
+	if version > SchemaVersion {
+		return fmt.Errorf("%w: schema version %d is newer than %d", ErrInvalidSnapshot, version, SchemaVersion)
+	}
+
+	return nil
+}
+
+// storedTables returns the tables of the database that hold rows themselves. The FTS5 check of a
+// virtual table needs to write, its rows are in shadow tables that are checked instead.
+func storedTables(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SELECT name FROM sqlite_schema WHERE type = 'table' AND sql NOT LIKE 'CREATE VIRTUAL TABLE%';")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+
+		tables = append(tables, name)
+	}
+
+	return tables, rows.Err()
+}
+
+// replaceFile copies source next to target and renames it over target, so target is never left
+// half written.
+func replaceFile(source string, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.CreateTemp(filepath.Dir(target), ".restore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	//? journals of the replaced database would be applied to the restored one
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if err := os.Remove(target + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return os.Rename(out.Name(), target)
+}
+
+func snapshotOf(file string) (Snapshot, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	return Snapshot{
+		Name:      filepath.Base(file),
+		Size:      info.Size(),
+		CreatedAt: info.ModTime().UTC(),
+	}, nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckSnapshotLeavesTheSnapshotAlone(t *testing.T) {
+	useTestDB(t)
+
+	if err := seedFixtures(mustOpenDB(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	backup, err := BackupDB(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(dir, backup.Name)
+	before, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkSnapshot(file); err != nil {
+		t.Fatalf("the backup is not usable: %v", err)
+	}
+
+	after, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(before, after) {
+		t.Error("checking the snapshot changed it")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		t.Errorf("checking the snapshot left %d files in the directory", len(entries))
+	}
+
+	if _, err := RestoreDB(dir, backup.Name); err != nil {
+		t.Errorf("restoring the backup: %v", err)
+	}
+
+	broken := filepath.Join(dir, "broken.db")
+	if err := os.WriteFile(broken, []byte("not a database"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkSnapshot(broken); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Errorf("a file that is no database was checked as %v", err)
+	}
+}
+
+func mustOpenDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := openDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"database/sql"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	openSlotsDesc = prometheus.NewDesc("travelagency_holiday_open_slots", "Free slots of each holiday that is not deleted.", []string{"holiday"}, nil)
+	soldOutDesc   = prometheus.NewDesc("travelagency_holidays_sold_out", "Holidays that are not deleted and have no free slots.", nil, nil)
+)
+
+// BookingCollector reads the booking gauges from the database on every scrape so they never drift
+// from the stored slots.
+type BookingCollector struct {
+	db *sql.DB //? nil to use the shared pool, which is reopened after a restart of the database
+}
+
+func NewBookingCollector(db *sql.DB) (*BookingCollector, error) {
+	return &BookingCollector{db: db}, nil
+}
+
+func (collector *BookingCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- openSlotsDesc
+	descs <- soldOutDesc
+}
+
+func (collector *BookingCollector) Collect(metrics chan<- prometheus.Metric) {
+	db := collector.db
+	if db == nil {
+		shared, err := openDB()
+		if err != nil {
+			metrics <- prometheus.NewInvalidMetric(openSlotsDesc, err)
+			return
+		}
+
+		db = shared
+	}
+
+	rows, err := db.Query("SELECT id, freeSlots FROM holidays WHERE deletedAt IS NULL;")
+	if err != nil {
+		metrics <- prometheus.NewInvalidMetric(openSlotsDesc, err)
+		return
+	}
+	defer rows.Close()
+
+	soldOut := 0
+	for rows.Next() {
+		var id int64
+		var freeSlots int
+		if err := rows.Scan(&id, &freeSlots); err != nil {
+			metrics <- prometheus.NewInvalidMetric(openSlotsDesc, err)
+			return
+		}
+
+		if freeSlots <= 0 {
+			soldOut++
+		}
+
+		metrics <- prometheus.MustNewConstMetric(openSlotsDesc, prometheus.GaugeValue, float64(freeSlots), strconv.FormatInt(id, 10))
+	}
+
+	if err := rows.Err(); err != nil {
+		metrics <- prometheus.NewInvalidMetric(soldOutDesc, err)
+		return
+	}
+
+	metrics <- prometheus.MustNewConstMetric(soldOutDesc, prometheus.GaugeValue, float64(soldOut))
+}
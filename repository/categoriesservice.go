@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// ErrCategoryHasHolidays is returned when deleting a category holidays still refer to, deleted ones included.
+var ErrCategoryHasHolidays = errors.New("category has holidays, move them to another category first")
+
+type CategoriesEntity struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// SetActor sets who is recorded in the audit log for the changes made through the repo.
+func (cat *CategoriesRepo) SetActor(actor string) {
+	cat.actor = actor
+}
+
+// SetContext sets the context the statements of the repo are traced in.
+func (cat *CategoriesRepo) SetContext(ctx context.Context) {
+	cat.db.setContext(ctx)
+}
+
+func (cat *CategoriesRepo) traceContext() context.Context {
+	return cat.db.ctx
+}
+
+func (cat *CategoriesRepo) Insert(entity CategoriesEntity) (*CategoriesEntity, error) {
+	defer startSpan(cat, "CategoriesRepo.Insert")()
+
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+
+	tx, err := cat.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	resp, err := tx.Exec("INSERT INTO categories (name) VALUES(?);", entity.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := resp.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(tx, cat.actor, AuditEntityCategory, id, nil); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &CategoriesEntity{
+		ID:   id,
+		Name: entity.Name,
+	}, nil
+}
+
+func (cat *CategoriesRepo) Update(entity CategoriesEntity) (*CategoriesEntity, error) {
+	defer startSpan(cat, "CategoriesRepo.Update")()
+
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+
+	tx, err := cat.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotRow(tx, AuditEntityCategory, entity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if before == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	_, err = tx.Exec("UPDATE categories SET name = ? WHERE id = ?;", entity.Name, entity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(tx, cat.actor, AuditEntityCategory, entity.ID, before); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+func (cat *CategoriesRepo) GetAll() ([]CategoriesEntity, error) {
+	defer startSpan(cat, "CategoriesRepo.GetAll")()
+
+	rows, err := cat.db.Query("SELECT id, name FROM categories ORDER BY name;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []CategoriesEntity{}
+	for rows.Next() {
+		entity := CategoriesEntity{}
+		err = rows.Scan(&entity.ID, &entity.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, entity)
+	}
+
+	return data, nil
+}
+
+func (cat *CategoriesRepo) GetByID(id int64) (*CategoriesEntity, error) {
+	defer startSpan(cat, "CategoriesRepo.GetByID")()
+
+	row := cat.db.QueryRow("SELECT id, name FROM categories WHERE id = ?;", id)
+
+	entity := CategoriesEntity{}
+	if err := row.Scan(&entity.ID, &entity.Name); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+// Delete removes the category, it returns ErrCategoryHasHolidays while holidays refer to it.
+func (cat *CategoriesRepo) Delete(id int64) error {
+	defer startSpan(cat, "CategoriesRepo.Delete")()
+
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+
+	tx, err := cat.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotRow(tx, AuditEntityCategory, id)
+	if err != nil {
+		return err
+	}
+
+	if before == nil {
+		return sql.ErrNoRows
+	}
+
+	//? holidays.categoryId has no ON DELETE, the foreign key would reject the delete
+	var used bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM holidays WHERE categoryId = ?);", id).Scan(&used); err != nil {
+		return err
+	}
+
+	if used {
+		return ErrCategoryHasHolidays
+	}
+
+	if _, err := tx.Exec("DELETE FROM categories WHERE id = ?;", id); err != nil {
+		return err
+	}
+
+	if err := recordAudit(tx, cat.actor, AuditEntityCategory, id, before); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
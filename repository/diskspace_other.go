@@ -0,0 +1,7 @@
+//go:build !unix
+
+package repository
+
+func freeDiskBytes(directory string) (uint64, error) {
+	return 0, ErrDiskSpaceUnsupported
+}
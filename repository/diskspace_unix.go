@@ -0,0 +1,14 @@
+//go:build unix
+
+package repository
+
+import "syscall"
+
+func freeDiskBytes(directory string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(directory, &stat); err != nil {
+		return 0, err
+	}
+
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -2,47 +2,144 @@ package repository
 
 import (
 	"database/sql"
+	"fmt"
 	"os"
 	"sync"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
 // TODO: add prepared statements / defer stmt.Close() when you call them
 
 var (
 	databaseFile     string = "sqlite.db"
-	connectionString string = "file:" + databaseFile + "?_foreign_keys=1"
+	connectionString string = "file:" + databaseFile + "?_foreign_keys=1&_busy_timeout=5000&_txlock=immediate"
+)
+
+// sharedDB is the connection pool of the repos created without a db.
+var (
+	sharedMu sync.Mutex
+	sharedDB *sql.DB
 )
 
+func openDB() (*sql.DB, error) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	if sharedDB == nil {
+		db, err := sql.Open(driverName, connectionString)
+		if err != nil {
+			return nil, err
+		}
+
+		sharedDB = db
+	}
+
+	return sharedDB, nil
+}
+
+// CloseDB waits for the running statements and closes the shared connection pool, repos created
+// afterwards open a new one.
+func CloseDB() error {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	if sharedDB == nil {
+		return nil
+	}
+
+	err := sharedDB.Close()
+	sharedDB = nil
+	return err
+}
+
+// Configure sets the database file and the connection string opening it, call it before the first repo
+// is created.
+func Configure(file string, dsn string) {
+	databaseFile = file
+	connectionString = dsn
+}
+
+// execQuerier is implemented by both *sql.DB and *sql.Tx.
+type execQuerier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
 type LocationsRepo struct {
-	mu sync.Mutex
-	db *sql.DB
+	mu    sync.Mutex
+	db    *tracedDB
+	actor string
+
+	searchEnabled bool
 }
 
-type HolidaysRepo struct {
-	mu sync.Mutex
-	db *sql.DB
+type LocationImagesRepo struct {
+	mu    sync.Mutex
+	db    *tracedDB
+	actor string
+}
+
+type CategoriesRepo struct {
+	mu    sync.Mutex
+	db    *tracedDB
+	actor string
+}
 
-	locationRepo *LocationsRepo
+type TagsRepo struct {
+	mu    sync.Mutex
+	db    *tracedDB
+	actor string
+}
+
+type HolidaysRepo struct {
+	mu    sync.Mutex
+	db    *tracedDB
+	actor string
+
+	locationRepo  *LocationsRepo
+	categoryRepo  *CategoriesRepo
+	tagRepo       *TagsRepo
+	searchEnabled bool
 }
 
 type ReservationsRepo struct {
-	mu sync.Mutex
-	db *sql.DB
+	mu    sync.Mutex
+	db    *tracedDB
+	actor string
 
 	holidayRepo *HolidaysRepo
 }
 
+type AuditRepo struct {
+	db *tracedDB
+}
+
+type ReviewsRepo struct {
+	mu    sync.Mutex
+	db    *tracedDB
+	actor string
+
+	reservationRepo *ReservationsRepo
+}
+
+type IdempotencyRepo struct {
+	db *tracedDB
+}
+
 func EnsureDBExists() error {
 	if _, err := os.Stat(databaseFile); err == nil {
-		return nil
+		return MigrateDB()
 	}
 
 	return CreateDB()
 }
 
 func RestartDB() error {
+	//? open connections would keep using the removed file
+	if err := CloseDB(); err != nil {
+		return err
+	}
+
 	if _, err := os.Stat(databaseFile); err == nil {
 		err := os.Remove(databaseFile)
 		if err != nil {
@@ -59,16 +156,37 @@ func CreateDB() error {
 		return err
 	}
 
-	db, err := sql.Open("sqlite3", connectionString)
+	return MigrateDB()
+}
+
+// MigrateDB creates the missing tables and columns of the database and records SchemaVersion.
+func MigrateDB() error {
+	db, err := sql.Open(driverName, connectionString)
 	if err != nil {
 		return err
 	}
+	defer db.Close()
 
 	_, err = NewLocationsRepo(db)
 	if err != nil {
 		return err
 	}
 
+	_, err = NewLocationImagesRepo(db)
+	if err != nil {
+		return err
+	}
+
+	_, err = NewCategoriesRepo(db)
+	if err != nil {
+		return err
+	}
+
+	_, err = NewTagsRepo(db)
+	if err != nil {
+		return err
+	}
+
 	_, err = NewHolidaysRepo(db)
 	if err != nil {
 		return err
@@ -79,13 +197,30 @@ func CreateDB() error {
 		return err
 	}
 
-	return nil
+	_, err = NewReviewsRepo(db)
+	if err != nil {
+		return err
+	}
+
+	_, err = NewIdempotencyRepo(db)
+	if err != nil {
+		return err
+	}
+
+	ready, err := ensureSearchIndex(db)
+	if err != nil {
+		return err
+	}
+	searchIndexReady.Store(ready)
+
+	_, err = db.Exec(fmt.Sprintf("PRAGMA user_version = %d;", SchemaVersion))
+	return err
 }
 
 func NewLocationsRepo(db *sql.DB) (*LocationsRepo, error) {
 	var err error
 	if db == nil {
-		db, err = sql.Open("sqlite3", connectionString)
+		db, err = openDB()
 		if err != nil {
 			return nil, err
 		}
@@ -98,22 +233,146 @@ func NewLocationsRepo(db *sql.DB) (*LocationsRepo, error) {
 		number TEXT NOT NULL,
 		city TEXT NOT NULL,
 		country TEXT NOT NULL,
-		imageUrl TEXT NOT NULL
+		imageUrl TEXT NOT NULL,
+		latitude REAL,
+		longitude REAL,
+		deletedAt TEXT,
+		version INTEGER NOT NULL DEFAULT 1
 	);`
 
 	if _, err := db.Exec(createStatement); err != nil {
 		return nil, err
 	}
 
+	//? databases created before coordinates, soft deletes and versions lack these columns
+	if err := ensureColumn(db, "locations", "latitude", "REAL"); err != nil {
+		return nil, err
+	}
+
+	if err := ensureColumn(db, "locations", "longitude", "REAL"); err != nil {
+		return nil, err
+	}
+
+	if err := ensureColumn(db, "locations", "deletedAt", "TEXT"); err != nil {
+		return nil, err
+	}
+
+	if err := ensureColumn(db, "locations", "version", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+		return nil, err
+	}
+
+	//? location and holiday ratings are read from the reviews
+	if err := createReviewsTable(db); err != nil {
+		return nil, err
+	}
+
+	if err := createAuditLogTable(db); err != nil {
+		return nil, err
+	}
+
 	return &LocationsRepo{
-		db: db,
+		db:            newTracedDB(db),
+		searchEnabled: searchIndexReady.Load(),
+	}, nil
+}
+
+func NewLocationImagesRepo(db *sql.DB) (*LocationImagesRepo, error) {
+	var err error
+	if db == nil {
+		db, err = openDB()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	createStatement := `
+	CREATE TABLE IF NOT EXISTS location_images (
+		id INTEGER NOT NULL PRIMARY KEY,
+		locationId INTEGER NOT NULL,
+		position INTEGER NOT NULL,
+		isCover INTEGER NOT NULL DEFAULT 0,
+		contentType TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		width INTEGER NOT NULL,
+		height INTEGER NOT NULL,
+		imageKey TEXT NOT NULL,
+		thumbnailKey TEXT NOT NULL,
+		FOREIGN KEY(locationId) REFERENCES locations(id) ON DELETE CASCADE
+	);`
+
+	if _, err := db.Exec(createStatement); err != nil {
+		return nil, err
+	}
+
+	if err := createAuditLogTable(db); err != nil {
+		return nil, err
+	}
+
+	return &LocationImagesRepo{
+		db: newTracedDB(db),
+	}, nil
+}
+
+func NewCategoriesRepo(db *sql.DB) (*CategoriesRepo, error) {
+	var err error
+	if db == nil {
+		db, err = openDB()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	createStatement := `
+	CREATE TABLE IF NOT EXISTS categories (
+		id INTEGER NOT NULL PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE
+	);`
+
+	if _, err := db.Exec(createStatement); err != nil {
+		return nil, err
+	}
+
+	if err := createAuditLogTable(db); err != nil {
+		return nil, err
+	}
+
+	return &CategoriesRepo{
+		db: newTracedDB(db),
+	}, nil
+}
+
+func NewTagsRepo(db *sql.DB) (*TagsRepo, error) {
+	var err error
+	if db == nil {
+		db, err = openDB()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	createStatement := `
+	CREATE TABLE IF NOT EXISTS tags (
+		id INTEGER NOT NULL PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE
+	);`
+
+	if _, err := db.Exec(createStatement); err != nil {
+		return nil, err
+	}
+
+	if err := createAuditLogTable(db); err != nil {
+		return nil, err
+	}
+
+	return &TagsRepo{
+		db: newTracedDB(db),
 	}, nil
 }
 
 func NewHolidaysRepo(db *sql.DB) (*HolidaysRepo, error) {
 	var err error
 	if db == nil {
-		db, err = sql.Open("sqlite3", connectionString)
+		db, err = openDB()
 		if err != nil {
 			return nil, err
 		}
@@ -128,28 +387,66 @@ func NewHolidaysRepo(db *sql.DB) (*HolidaysRepo, error) {
 		price REAL NOT NULL,
 		freeSlots INTEGER NOT NULL,
 		locationId INTEGER NOT NULL,
+		categoryId INTEGER REFERENCES categories(id),
+		deletedAt TEXT,
+		version INTEGER NOT NULL DEFAULT 1,
 		FOREIGN KEY(locationId) REFERENCES locations(id)
+	);
+
+	CREATE TABLE IF NOT EXISTS holiday_tags (
+		holidayId INTEGER NOT NULL,
+		tagId INTEGER NOT NULL,
+		PRIMARY KEY(holidayId, tagId),
+		FOREIGN KEY(holidayId) REFERENCES holidays(id) ON DELETE CASCADE,
+		FOREIGN KEY(tagId) REFERENCES tags(id) ON DELETE CASCADE
 	);`
 
 	if _, err := db.Exec(createStatement); err != nil {
 		return nil, err
 	}
 
+	//? databases created before categories were introduced lack this column
+	if err := ensureColumn(db, "holidays", "categoryId", "INTEGER REFERENCES categories(id)"); err != nil {
+		return nil, err
+	}
+
+	//? databases created before soft deletes and versions lack these columns
+	if err := ensureColumn(db, "holidays", "deletedAt", "TEXT"); err != nil {
+		return nil, err
+	}
+
+	if err := ensureColumn(db, "holidays", "version", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+		return nil, err
+	}
+
 	locationRepo, err := NewLocationsRepo(db)
 	if err != nil {
 		return nil, err
 	}
 
+	categoryRepo, err := NewCategoriesRepo(db)
+	if err != nil {
+		return nil, err
+	}
+
+	tagRepo, err := NewTagsRepo(db)
+	if err != nil {
+		return nil, err
+	}
+
 	return &HolidaysRepo{
-		db:           db,
-		locationRepo: locationRepo,
+		db:            newTracedDB(db),
+		locationRepo:  locationRepo,
+		categoryRepo:  categoryRepo,
+		tagRepo:       tagRepo,
+		searchEnabled: searchIndexReady.Load(),
 	}, nil
 }
 
 func NewReservationsRepo(db *sql.DB) (*ReservationsRepo, error) {
 	var err error
 	if db == nil {
-		db, err = sql.Open("sqlite3", connectionString)
+		db, err = openDB()
 		if err != nil {
 			return nil, err
 		}
@@ -161,6 +458,8 @@ func NewReservationsRepo(db *sql.DB) (*ReservationsRepo, error) {
 		contactName TEXT NOT NULL,
 		phoneNumber TEXT NOT NULL,
 		holidayId INTEGER NOT NULL,
+		deletedAt TEXT,
+		version INTEGER NOT NULL DEFAULT 1,
 		FOREIGN KEY(holidayId) REFERENCES holidays(id)
 	);`
 
@@ -168,13 +467,174 @@ func NewReservationsRepo(db *sql.DB) (*ReservationsRepo, error) {
 		return nil, err
 	}
 
+	//? databases created before soft deletes and versions lack these columns
+	if err := ensureColumn(db, "reservations", "deletedAt", "TEXT"); err != nil {
+		return nil, err
+	}
+
+	if err := ensureColumn(db, "reservations", "version", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+		return nil, err
+	}
+
 	holidayRepo, err := NewHolidaysRepo(db)
 	if err != nil {
 		return nil, err
 	}
 
 	return &ReservationsRepo{
-		db:          db,
+		db:          newTracedDB(db),
 		holidayRepo: holidayRepo,
 	}, nil
 }
+
+func NewReviewsRepo(db *sql.DB) (*ReviewsRepo, error) {
+	var err error
+	if db == nil {
+		db, err = openDB()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := createReviewsTable(db); err != nil {
+		return nil, err
+	}
+
+	reservationRepo, err := NewReservationsRepo(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReviewsRepo{
+		db:              newTracedDB(db),
+		reservationRepo: reservationRepo,
+	}, nil
+}
+
+func NewAuditRepo(db *sql.DB) (*AuditRepo, error) {
+	var err error
+	if db == nil {
+		db, err = openDB()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := createAuditLogTable(db); err != nil {
+		return nil, err
+	}
+
+	return &AuditRepo{
+		db: newTracedDB(db),
+	}, nil
+}
+
+func NewIdempotencyRepo(db *sql.DB) (*IdempotencyRepo, error) {
+	var err error
+	if db == nil {
+		db, err = openDB()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	createStatement := `
+	CREATE TABLE IF NOT EXISTS idempotency_keys (
+		caller TEXT NOT NULL,
+		key TEXT NOT NULL,
+		requestHash TEXT NOT NULL,
+		status INTEGER NOT NULL DEFAULT 0,
+		header TEXT NOT NULL DEFAULT '{}',
+		content BLOB,
+		createdAt TEXT NOT NULL,
+		PRIMARY KEY(caller, key)
+	);
+
+	CREATE INDEX IF NOT EXISTS idempotency_keys_created ON idempotency_keys(createdAt);`
+
+	if _, err := db.Exec(createStatement); err != nil {
+		return nil, err
+	}
+
+	return &IdempotencyRepo{
+		db: newTracedDB(db),
+	}, nil
+}
+
+func createAuditLogTable(db *sql.DB) error {
+	createStatement := `
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER NOT NULL PRIMARY KEY,
+		actor TEXT NOT NULL,
+		createdAt TEXT NOT NULL,
+		entityType TEXT NOT NULL,
+		entityId INTEGER NOT NULL,
+		action TEXT NOT NULL,
+		diff TEXT NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS audit_log_entity ON audit_log(entityType, entityId);`
+
+	if _, err := db.Exec(createStatement); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(createAuditLogTriggers)
+	return err
+}
+
+// createAuditLogTriggers keep the audit log append-only.
+const createAuditLogTriggers = `
+	CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+	BEGIN
+		SELECT RAISE(ABORT, 'audit_log is append-only');
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+	BEGIN
+		SELECT RAISE(ABORT, 'audit_log is append-only');
+	END;`
+
+func createReviewsTable(db *sql.DB) error {
+	createStatement := `
+	CREATE TABLE IF NOT EXISTS reviews (
+		id INTEGER NOT NULL PRIMARY KEY,
+		reservationId INTEGER NOT NULL UNIQUE,
+		holidayId INTEGER NOT NULL,
+		rating INTEGER NOT NULL CHECK (rating BETWEEN 1 AND 5),
+		text TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		createdAt TEXT NOT NULL,
+		FOREIGN KEY(reservationId) REFERENCES reservations(id) ON DELETE CASCADE,
+		FOREIGN KEY(holidayId) REFERENCES holidays(id)
+	);`
+
+	_, err := db.Exec(createStatement)
+	return err
+}
+
+func ensureColumn(db *sql.DB, table string, column string, definition string) error {
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?);", table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+
+		if name == column {
+			return nil
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition + ";")
+	return err
+}
@@ -0,0 +1,31 @@
+-- Sample data seeded by ResetDB and ClearDB, ids are fixed so that requests in tests can refer to them.
+INSERT INTO locations (id, street, number, city, country, imageUrl, latitude, longitude) VALUES
+	(1, 'Ulitsa Morska', '12', 'Sozopol', 'Bulgaria', '', 42.4178, 27.6956),
+	(2, 'Rua Augusta', '24', 'Lisbon', 'Portugal', '', 38.7107, -9.1366),
+	(3, 'Via del Corso', '5', 'Rome', 'Italy', '', 41.9029, 12.4797);
+
+INSERT INTO categories (id, name) VALUES
+	(1, 'Beach'),
+	(2, 'City break'),
+	(3, 'Culture');
+
+INSERT INTO tags (id, name) VALUES
+	(1, 'family'),
+	(2, 'all inclusive'),
+	(3, 'romantic');
+
+INSERT INTO holidays (id, title, startDate, duration, price, freeSlots, locationId, categoryId) VALUES
+	(1, 'Sunny Sozopol week', '2027-07-05', 7, 899.0, 20, 1, 1),
+	(2, 'Lisbon long weekend', '2027-04-15', 4, 549.5, 12, 2, 2),
+	(3, 'Rome in autumn', '2027-10-01', 5, 720.0, 15, 3, 3);
+
+INSERT INTO holiday_tags (holidayId, tagId) VALUES
+	(1, 1),
+	(1, 2),
+	(2, 3),
+	(3, 3);
+
+INSERT INTO reservations (id, contactName, phoneNumber, holidayId) VALUES
+	(1, 'Maria Petrova', '+359888123456', 1);
+
+UPDATE holidays SET freeSlots = freeSlots - 1 WHERE id = 1;
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"math"
+	"sort"
+)
+
+const earthRadiusKm = 6371.0
+
+type GeoPoint struct {
+	Latitude  float64
+	Longitude float64
+}
+
+type boundingBox struct {
+	MinLatitude  float64
+	MaxLatitude  float64
+	MinLongitude float64
+	MaxLongitude float64
+}
+
+// distanceKm returns the great-circle distance between two points using the haversine formula.
+func distanceKm(from GeoPoint, to GeoPoint) float64 {
+	fromLat := from.Latitude * math.Pi / 180
+	toLat := to.Latitude * math.Pi / 180
+	deltaLat := (to.Latitude - from.Latitude) * math.Pi / 180
+	deltaLng := (to.Longitude - from.Longitude) * math.Pi / 180
+
+	a := math.Sin(deltaLat/2)*math.Sin(deltaLat/2) + math.Cos(fromLat)*math.Cos(toLat)*math.Sin(deltaLng/2)*math.Sin(deltaLng/2)
+	return 2 * earthRadiusKm * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// boundingBoxFor returns a box that contains every point within radiusKm of center.
+// It is only a prefilter, the exact distance is checked afterwards.
+func boundingBoxFor(center GeoPoint, radiusKm float64) boundingBox {
+	deltaLat := radiusKm / earthRadiusKm * 180 / math.Pi
+	box := boundingBox{
+		MinLatitude:  center.Latitude - deltaLat,
+		MaxLatitude:  center.Latitude + deltaLat,
+		MinLongitude: -180,
+		MaxLongitude: 180,
+	}
+
+	//? near the poles every longitude can be within the radius
+	if box.MinLatitude <= -90 || box.MaxLatitude >= 90 {
+		box.MinLatitude = math.Max(box.MinLatitude, -90)
+		box.MaxLatitude = math.Min(box.MaxLatitude, 90)
+		return box
+	}
+
+	deltaLng := deltaLat / math.Cos(center.Latitude*math.Pi/180)
+	if deltaLng < 180 {
+		box.MinLongitude = center.Longitude - deltaLng
+		box.MaxLongitude = center.Longitude + deltaLng
+	}
+
+	return box
+}
+
+// sqlCondition returns the WHERE fragment and its arguments for the given latitude and longitude columns.
+func (box boundingBox) sqlCondition(latitudeColumn string, longitudeColumn string) (string, []interface{}) {
+	condition := " AND " + latitudeColumn + " BETWEEN ? AND ? "
+	args := []interface{}{box.MinLatitude, box.MaxLatitude}
+
+	switch {
+	case box.MinLongitude < -180:
+		//? the box crosses the antimeridian on the west side
+		condition += " AND (" + longitudeColumn + " >= ? OR " + longitudeColumn + " <= ?) "
+		args = append(args, box.MinLongitude+360, box.MaxLongitude)
+	case box.MaxLongitude > 180:
+		//? the box crosses the antimeridian on the east side
+		condition += " AND (" + longitudeColumn + " >= ? OR " + longitudeColumn + " <= ?) "
+		args = append(args, box.MinLongitude, box.MaxLongitude-360)
+	default:
+		condition += " AND " + longitudeColumn + " BETWEEN ? AND ? "
+		args = append(args, box.MinLongitude, box.MaxLongitude)
+	}
+
+	return condition, args
+}
+
+func sortByDistance[T any](data []T, distance func(T) float64) {
+	sort.SliceStable(data, func(i, j int) bool {
+		return distance(data[i]) < distance(data[j])
+	})
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"testing"
+)
+
+func TestBoundingBoxFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		center   GeoPoint
+		radiusKm float64
+		inside   []GeoPoint
+		outside  []GeoPoint
+	}{
+		{
+			name:     "away from the antimeridian and the poles",
+			center:   GeoPoint{Latitude: 38.72, Longitude: -9.14},
+			radiusKm: 300,
+			inside:   []GeoPoint{{Latitude: 37.02, Longitude: -7.93}},
+			outside:  []GeoPoint{{Latitude: 40.42, Longitude: -3.70}, {Latitude: 38.72, Longitude: 170.86}},
+		},
+		{
+			name:     "crossing the antimeridian on the east side",
+			center:   GeoPoint{Latitude: -18.14, Longitude: 178.44},
+			radiusKm: 1500,
+			inside:   []GeoPoint{{Latitude: -21.14, Longitude: -175.20}, {Latitude: -13.83, Longitude: -171.77}},
+			outside:  []GeoPoint{{Latitude: -18.14, Longitude: 0}, {Latitude: -18.14, Longitude: -150}},
+		},
+		{
+			name:     "crossing the antimeridian on the west side",
+			center:   GeoPoint{Latitude: -13.83, Longitude: -171.77},
+			radiusKm: 1500,
+			inside:   []GeoPoint{{Latitude: -18.14, Longitude: 178.44}},
+			outside:  []GeoPoint{{Latitude: -13.83, Longitude: 150}},
+		},
+		{
+			name:     "near the north pole",
+			center:   GeoPoint{Latitude: 89.5, Longitude: 10},
+			radiusKm: 200,
+			inside:   []GeoPoint{{Latitude: 89.5, Longitude: -170}, {Latitude: 90, Longitude: 0}},
+			outside:  []GeoPoint{{Latitude: 85, Longitude: 10}},
+		},
+		{
+			name:     "near the south pole",
+			center:   GeoPoint{Latitude: -89.9, Longitude: 139.27},
+			radiusKm: 100,
+			inside:   []GeoPoint{{Latitude: -89.8, Longitude: -40.73}},
+			outside:  []GeoPoint{{Latitude: -88, Longitude: 139.27}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			box := boundingBoxFor(test.center, test.radiusKm)
+			if box.MinLatitude < -90 || box.MaxLatitude > 90 {
+				t.Errorf("the latitudes %f to %f leave the globe", box.MinLatitude, box.MaxLatitude)
+			}
+
+			for _, point := range test.inside {
+				if distance := distanceKm(test.center, point); distance > test.radiusKm {
+					t.Fatalf("the test point %v is %f km away", point, distance)
+				}
+
+				if !boxContains(box, point) {
+					t.Errorf("the box %+v misses %v", box, point)
+				}
+			}
+
+			for _, point := range test.outside {
+				if boxContains(box, point) {
+					t.Errorf("the box %+v contains %v", box, point)
+				}
+			}
+		})
+	}
+}
+
+// boxContains evaluates the condition of the box the way SQLite does.
+func boxContains(box boundingBox, point GeoPoint) bool {
+	_, args := box.sqlCondition("latitude", "longitude")
+	bound := func(i int) float64 { return args[i].(float64) }
+
+	if point.Latitude < bound(0) || point.Latitude > bound(1) {
+		return false
+	}
+
+	//? the longitude is either BETWEEN the bounds or, across the antimeridian, >= the first OR <= the second
+	if bound(2) > bound(3) {
+		return point.Longitude >= bound(2) || point.Longitude <= bound(3)
+	}
+
+	return point.Longitude >= bound(2) && point.Longitude <= bound(3)
+}
+
+func TestGetNearSortsByDistanceAcrossTheAntimeridian(t *testing.T) {
+	useTestDB(t)
+
+	locations, err := NewLocationsRepo(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	places := []struct {
+		city      string
+		latitude  float64
+		longitude float64
+	}{
+		{"Apia", -13.83, -171.77},
+		{"Lisbon", 38.72, -9.14},
+		{"Nadi", -17.80, 177.42},
+		{"Nuku'alofa", -21.14, -175.20},
+	}
+	for _, place := range places {
+		latitude, longitude := place.latitude, place.longitude
+		if _, err := locations.Insert(LocationsEntity{City: place.city, Country: "-", Latitude: &latitude, Longitude: &longitude}); err != nil {
+			t.Fatalf("inserting %s: %v", place.city, err)
+		}
+	}
+
+	near, err := locations.GetNear(GeoPoint{Latitude: -18.14, Longitude: 178.44}, 1500, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cities := []string{}
+	for _, location := range near {
+		cities = append(cities, location.City)
+	}
+
+	expected := []string{"Nadi", "Nuku'alofa", "Apia"}
+	if len(cities) != len(expected) {
+		t.Fatalf("found %v, expected %v", cities, expected)
+	}
+
+	for i := range expected {
+		if cities[i] != expected[i] {
+			t.Fatalf("found %v, expected %v", cities, expected)
+		}
+	}
+}
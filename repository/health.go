@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// SchemaVersion is recorded in the user_version of the database by MigrateDB, raise it with every
+// table or column the constructors add.
+const SchemaVersion = 1
+
+// ErrDiskSpaceUnsupported is returned by FreeDiskBytes on the platforms it can not read the free space on.
+var ErrDiskSpaceUnsupported = errors.New("free disk space is only checked on unix")
+
+// PingDB checks that the database file exists and answers a connection.
+func PingDB(ctx context.Context) error {
+	//? opening a missing file would quietly create an empty database
+	if _, err := os.Stat(databaseFile); err != nil {
+		return err
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	return db.PingContext(ctx)
+}
+
+// CheckSchemaVersion fails unless the database was migrated to SchemaVersion.
+func CheckSchemaVersion(ctx context.Context) (int, error) {
+	db, err := openDB()
+	if err != nil {
+		return 0, err
+	}
+
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version;").Scan(&version); err != nil {
+		return 0, err
+	}
+
+	if version != SchemaVersion {
+		return version, fmt.Errorf("schema version %d, expected %d", version, SchemaVersion)
+	}
+
+	return version, nil
+}
+
+// FreeDiskBytes returns the space left to the database on its file system.
+func FreeDiskBytes() (uint64, error) {
+	directory, err := filepath.Abs(filepath.Dir(databaseFile))
+	if err != nil {
+		return 0, err
+	}
+
+	return freeDiskBytes(directory)
+}
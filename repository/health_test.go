@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"testing"
+)
+
+func TestProbesUseTheSharedPool(t *testing.T) {
+	useTestDB(t)
+
+	db, err := openDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := PingDB(context.Background()); err != nil {
+			t.Fatalf("ping: %v", err)
+		}
+
+		if _, err := CheckSchemaVersion(context.Background()); err != nil {
+			t.Fatalf("schema version: %v", err)
+		}
+	}
+
+	//? a probe that closed the pool would leave the repos a closed one
+	if err := db.Ping(); err != nil {
+		t.Errorf("the shared pool is unusable after the probes: %v", err)
+	}
+
+	if current, _ := openDB(); current != db {
+		t.Errorf("the probes replaced the shared pool")
+	}
+}
@@ -1,32 +1,146 @@
 package repository
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+	"sort"
+)
 
 type HolidaysEntity struct {
-	ID         int64           `json:"id"`
-	Title      string          `json:"title"`
-	StartDate  string          `json:"startDate"`
-	Duration   int             `json:"duration"`
-	Price      float64         `json:"price"`
-	FreeSlots  int             `json:"freeSlots"`
-	LocationId int64           `json:"-"` //? used only to query the location entity from db
-	Location   LocationsEntity `json:"location"`
+	ID         int64             `json:"id"`
+	Title      string            `json:"title"`
+	StartDate  string            `json:"startDate"`
+	Duration   int               `json:"duration"`
+	Price      float64           `json:"price"`
+	FreeSlots  int               `json:"freeSlots"`
+	LocationId int64             `json:"-"` //? used only to query the location entity from db
+	Location   LocationsEntity   `json:"location"`
+	CategoryId *int64            `json:"-"` //? used only to query the category entity from db
+	Category   *CategoriesEntity `json:"category"`
+	TagIds     []int64           `json:"-"` //? used only to store the tags of the holiday
+	Tags       []TagsEntity      `json:"tags"`
+	Rating     RatingSummary     `json:"rating"`
+	DistanceKm *float64          `json:"distanceKm,omitempty"` //? set only when searching near a point
+	DeletedAt  *string           `json:"deletedAt,omitempty"`
+	Version    int64             `json:"version"`
+}
+
+type HolidaysFilter struct {
+	Location  string
+	StartDate string
+	Duration  string
+	Category  string
+	Tags      []string //? holidays must have every tag
+	Sort      string   //? HolidaysSortRating or empty for the default order
+
+	//? when Near is set only holidays within RadiusKm are returned, closest first
+	Near     *GeoPoint
+	RadiusKm float64
+
+	IncludeDeleted bool
+}
+
+type HolidayFacets struct {
+	Tags      map[string]int `json:"tags"`
+	Countries map[string]int `json:"countries"`
+	Durations map[string]int `json:"durations"`
+}
+
+const HolidaysSortRating = "rating"
+
+const holidayColumns = "h.id, h.title, h.startDate, h.duration, h.price, h.freeSlots, h.locationId, h.categoryId, h.deletedAt, h.version"
+
+func scanHoliday(row rowScanner, extra ...any) (*HolidaysEntity, error) {
+	entity := HolidaysEntity{}
+	var categoryId sql.NullInt64
+	var deletedAt sql.NullString
+
+	dest := append([]any{&entity.ID, &entity.Title, &entity.StartDate, &entity.Duration, &entity.Price, &entity.FreeSlots, &entity.LocationId, &categoryId, &deletedAt, &entity.Version}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	entity.DeletedAt = scanDeletedAt(deletedAt)
+
+	if categoryId.Valid {
+		entity.CategoryId = &categoryId.Int64
+	}
+
+	return &entity, nil
+}
+
+// loadRelations fills the location, category and tags of a holiday read from the db.
+func (hol *HolidaysRepo) loadRelations(entity *HolidaysEntity) error {
+	locationEntity, err := hol.locationRepo.GetByID(entity.LocationId)
+	if err != nil {
+		return err
+	}
+
+	entity.Location = *locationEntity
+
+	entity.Category = nil
+	if entity.CategoryId != nil {
+		entity.Category, err = hol.categoryRepo.GetByID(*entity.CategoryId)
+		if err != nil {
+			return err
+		}
+	}
+
+	entity.Tags, err = hol.tagRepo.GetByHolidayID(entity.ID)
+	if err != nil {
+		return err
+	}
+
+	entity.TagIds = make([]int64, 0, len(entity.Tags))
+	for _, tag := range entity.Tags {
+		entity.TagIds = append(entity.TagIds, tag.ID)
+	}
+
+	entity.Rating, err = holidayRating(hol.db, entity.ID)
+	return err
+}
+
+// SetActor sets who is recorded in the audit log for the changes made through the repo.
+func (hol *HolidaysRepo) SetActor(actor string) {
+	hol.actor = actor
+	hol.locationRepo.SetActor(actor)
+	hol.categoryRepo.SetActor(actor)
+	hol.tagRepo.SetActor(actor)
+}
+
+// SetContext sets the context the statements of the repo are traced in.
+func (hol *HolidaysRepo) SetContext(ctx context.Context) {
+	hol.db.setContext(ctx)
+	hol.locationRepo.SetContext(ctx)
+	hol.categoryRepo.SetContext(ctx)
+	hol.tagRepo.SetContext(ctx)
+}
+
+func (hol *HolidaysRepo) traceContext() context.Context {
+	return hol.db.ctx
 }
 
 func (hol *HolidaysRepo) Insert(entity HolidaysEntity) (*HolidaysEntity, error) {
+	defer startSpan(hol, "HolidaysRepo.Insert")()
+
 	hol.mu.Lock()
 	defer hol.mu.Unlock()
 
-	resp, err := hol.db.Exec("INSERT INTO holidays VALUES(NULL,?,?,?,?,?,?);", entity.Title, entity.StartDate, entity.Duration, entity.Price, entity.FreeSlots, entity.LocationId)
+	tx, err := hol.db.Begin()
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	id, err := resp.LastInsertId()
+	id, err := hol.insert(tx, entity)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
 	responseData := HolidaysEntity{
 		ID:         id,
 		Title:      entity.Title,
@@ -35,105 +149,400 @@ func (hol *HolidaysRepo) Insert(entity HolidaysEntity) (*HolidaysEntity, error)
 		Price:      entity.Price,
 		FreeSlots:  entity.FreeSlots,
 		LocationId: entity.LocationId,
+		CategoryId: entity.CategoryId,
+		Version:    1,
 	}
 
-	locationEntity, err := hol.locationRepo.GetByID(responseData.LocationId)
-	if err != nil {
+	if err := hol.loadRelations(&responseData); err != nil {
 		return nil, err
 	}
 
-	responseData.Location = *locationEntity
-	return &entity, nil
+	return &responseData, nil
+}
+
+// insert stores the holiday with its tags as part of tx and returns its id.
+func (hol *HolidaysRepo) insert(tx execQuerier, entity HolidaysEntity) (int64, error) {
+	deleted, err := isDeleted(tx, AuditEntityLocation, entity.LocationId)
+	if err != nil {
+		return 0, err
+	}
+
+	if deleted {
+		return 0, ErrLocationDeleted
+	}
+
+	resp, err := tx.Exec("INSERT INTO holidays (title, startDate, duration, price, freeSlots, locationId, categoryId) VALUES(?,?,?,?,?,?,?);", entity.Title, entity.StartDate, entity.Duration, entity.Price, entity.FreeSlots, entity.LocationId, entity.CategoryId)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := resp.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := setHolidayTags(tx, id, entity.TagIds); err != nil {
+		return 0, err
+	}
+
+	if hol.searchEnabled {
+		if err := indexHoliday(tx, id); err != nil {
+			return 0, err
+		}
+	}
+
+	return id, recordAudit(tx, hol.actor, AuditEntityHoliday, id, nil)
+}
+
+// Import stores the rows in one transaction, see importRows for the options.
+func (hol *HolidaysRepo) Import(rows []ImportRow[HolidaysEntity], options ImportOptions) (*ImportResult, error) {
+	defer startSpan(hol, "HolidaysRepo.Import")()
+
+	hol.mu.Lock()
+	defer hol.mu.Unlock()
+
+	return importRows(hol.db, rows, options, hol.insert)
 }
 
 func (hol *HolidaysRepo) Update(entity HolidaysEntity) (*HolidaysEntity, error) {
+	defer startSpan(hol, "HolidaysRepo.Update")()
+
 	hol.mu.Lock()
 	defer hol.mu.Unlock()
 
-	_, err := hol.db.Exec("UPDATE holidays SET title = ?, startDate = ?, duration = ?, price = ?, freeSlots = ?, locationId = ?  WHERE id = ?;", entity.Title, entity.StartDate, entity.Duration, entity.Price, entity.FreeSlots, entity.LocationId, entity.ID)
+	tx, err := hol.db.Begin()
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	locationEntity, err := hol.locationRepo.GetByID(entity.LocationId)
+	before, err := snapshotRow(tx, AuditEntityHoliday, entity.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	entity.Location = *locationEntity
+	if err := checkVersion(tx, AuditEntityHoliday, entity.ID, entity.Version); err != nil {
+		return nil, err
+	}
+
+	deleted, err := isDeleted(tx, AuditEntityLocation, entity.LocationId)
+	if err != nil {
+		return nil, err
+	}
+
+	if deleted {
+		return nil, ErrLocationDeleted
+	}
+
+	_, err = tx.Exec("UPDATE holidays SET title = ?, startDate = ?, duration = ?, price = ?, freeSlots = ?, locationId = ?, categoryId = ?, version = version + 1 WHERE id = ?;", entity.Title, entity.StartDate, entity.Duration, entity.Price, entity.FreeSlots, entity.LocationId, entity.CategoryId, entity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setHolidayTags(tx, entity.ID, entity.TagIds); err != nil {
+		return nil, err
+	}
+
+	if hol.searchEnabled {
+		if err := indexHoliday(tx, entity.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := recordAudit(tx, hol.actor, AuditEntityHoliday, entity.ID, before); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	entity.Version++
+	if err := hol.loadRelations(&entity); err != nil {
+		return nil, err
+	}
+
 	return &entity, nil
 }
 
-func (hol *HolidaysRepo) GetAll(location string, startDate string, duration string) ([]HolidaysEntity, error) {
-	query := "SELECT h.id, h.title, h.startDate, h.duration, h.price, h.freeSlots, h.locationId FROM holidays h JOIN locations l ON l.id = h.locationId WHERE 1=1"
+// conditions returns the WHERE fragment and its arguments for a query joining holidays h with locations l.
+func (filter HolidaysFilter) conditions() (string, []interface{}) {
+	condition := ""
 	args := []interface{}{}
 
-	if location != "" {
-		query += " AND (l.city = ? OR l.country = ?) "
-		args = append(args, location)
-		args = append(args, location)
+	if !filter.IncludeDeleted {
+		condition += " AND h.deletedAt IS NULL "
 	}
 
-	if startDate != "" {
-		query += " AND h.startDate = ? "
-		args = append(args, startDate)
+	if filter.Location != "" {
+		condition += " AND (l.city = ? OR l.country = ?) "
+		args = append(args, filter.Location)
+		args = append(args, filter.Location)
 	}
 
-	if duration != "" {
-		query += " AND h.duration = ? "
-		args = append(args, duration)
+	if filter.StartDate != "" {
+		condition += " AND h.startDate = ? "
+		args = append(args, filter.StartDate)
 	}
 
-	query += ";"
+	if filter.Duration != "" {
+		condition += " AND h.duration = ? "
+		args = append(args, filter.Duration)
+	}
+
+	if filter.Category != "" {
+		condition += " AND h.categoryId IN (SELECT id FROM categories WHERE name = ?) "
+		args = append(args, filter.Category)
+	}
+
+	if len(filter.Tags) > 0 {
+		placeholders := "?"
+		args = append(args, filter.Tags[0])
+		for _, tag := range filter.Tags[1:] {
+			placeholders += ",?"
+			args = append(args, tag)
+		}
+
+		condition += " AND h.id IN (SELECT ht.holidayId FROM holiday_tags ht JOIN tags t ON t.id = ht.tagId WHERE t.name IN (" + placeholders + ") GROUP BY ht.holidayId HAVING COUNT(DISTINCT t.id) = ?) "
+		args = append(args, len(filter.Tags))
+	}
+
+	if filter.Near != nil {
+		boxCondition, boxArgs := boundingBoxFor(*filter.Near, filter.RadiusKm).sqlCondition("l.latitude", "l.longitude")
+		condition += " AND l.latitude IS NOT NULL AND l.longitude IS NOT NULL " + boxCondition
+		args = append(args, boxArgs...)
+	}
+
+	return condition, args
+}
+
+// distanceFrom sets the distance of the holiday to the filter point and reports whether it is within the radius.
+func (filter HolidaysFilter) distanceFrom(entity *HolidaysEntity) bool {
+	if filter.Near == nil {
+		return true
+	}
+
+	distance := distanceKm(*filter.Near, *entity.Location.point())
+	entity.DistanceKm = &distance
+	return distance <= filter.RadiusKm
+}
+
+func (hol *HolidaysRepo) GetAll(filter HolidaysFilter) ([]HolidaysEntity, error) {
+	defer startSpan(hol, "HolidaysRepo.GetAll")()
+
+	data := []HolidaysEntity{}
+	err := hol.Each(filter, func(entity HolidaysEntity) error {
+		data = append(data, entity)
+		return nil
+	})
 
-	rows, err := hol.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
+
+	switch {
+	case filter.Sort == HolidaysSortRating:
+		sortByRating(data)
+	case filter.Near != nil:
+		sortByDistance(data, func(entity HolidaysEntity) float64 { return *entity.DistanceKm })
+	}
+
+	return data, nil
+}
+
+// Each calls fn with the holidays matching the filter one at a time in id order so they can be streamed.
+// filter.Sort is ignored, sorting needs every holiday and is done by GetAll.
+func (hol *HolidaysRepo) Each(filter HolidaysFilter, fn func(entity HolidaysEntity) error) error {
+	defer startSpan(hol, "HolidaysRepo.Each")()
+
+	condition, args := filter.conditions()
+	query := "SELECT " + holidayColumns + " FROM holidays h JOIN locations l ON l.id = h.locationId WHERE 1=1" + condition
+	query += " ORDER BY h.id;"
+
+	rows, err := hol.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
 	defer rows.Close()
 
-	data := []HolidaysEntity{}
 	for rows.Next() {
-		entity := HolidaysEntity{}
-		err = rows.Scan(&entity.ID, &entity.Title, &entity.StartDate, &entity.Duration, &entity.Price, &entity.FreeSlots, &entity.LocationId)
+		entity, err := scanHoliday(rows)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		locationEntity, err := hol.locationRepo.GetByID(entity.LocationId)
-		if err != nil {
-			return nil, err
+		if err := hol.loadRelations(entity); err != nil {
+			return err
 		}
 
-		entity.Location = *locationEntity
-		data = append(data, entity)
+		if !filter.distanceFrom(entity) {
+			continue
+		}
+
+		if err := fn(*entity); err != nil {
+			return err
+		}
 	}
 
-	return data, nil
+	return rows.Err()
 }
 
 func (hol *HolidaysRepo) GetByID(id int64) (*HolidaysEntity, error) {
-	row := hol.db.QueryRow("SELECT * FROM holidays WHERE id = ?;", id)
+	defer startSpan(hol, "HolidaysRepo.GetByID")()
 
-	var err error
-	entity := HolidaysEntity{}
-	if err = row.Scan(&entity.ID, &entity.Title, &entity.StartDate, &entity.Duration, &entity.Price, &entity.FreeSlots, &entity.LocationId); err == sql.ErrNoRows {
+	row := hol.db.QueryRow("SELECT "+holidayColumns+" FROM holidays h WHERE h.id = ?;", id)
+
+	entity, err := scanHoliday(row)
+	if err != nil {
 		return nil, err
 	}
 
-	locationEntity, err := hol.locationRepo.GetByID(entity.LocationId)
-	if err != nil {
+	if err := hol.loadRelations(entity); err != nil {
 		return nil, err
 	}
 
-	entity.Location = *locationEntity
-	return &entity, nil
+	return entity, nil
 }
 
-func (hol *HolidaysRepo) Delete(id int64) error {
+// Delete soft deletes the holiday when it is still at version, it is denied while the holiday has reservations.
+func (hol *HolidaysRepo) Delete(id int64, version int64) error {
+	defer startSpan(hol, "HolidaysRepo.Delete")()
+
 	hol.mu.Lock()
 	defer hol.mu.Unlock()
 
-	_, err := hol.db.Exec("DELETE FROM holidays WHERE id = ?;", id)
-	return err
+	tx, err := hol.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkVersion(tx, AuditEntityHoliday, id, version); err != nil {
+		return err
+	}
+
+	reservationIDs, err := activeChildIDs(tx, "reservations", "holidayId", id)
+	if err != nil {
+		return err
+	}
+
+	if len(reservationIDs) > 0 {
+		return ErrHolidayHasReservations
+	}
+
+	if err := markDeleted(tx, hol.actor, AuditEntityHoliday, id); err != nil {
+		return err
+	}
+
+	if hol.searchEnabled {
+		if err := removeHolidayFromIndex(tx, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Restore undoes the soft delete of the holiday, its location has to be restored first.
+func (hol *HolidaysRepo) Restore(id int64) error {
+	defer startSpan(hol, "HolidaysRepo.Restore")()
+
+	hol.mu.Lock()
+	defer hol.mu.Unlock()
+
+	tx, err := hol.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var locationID int64
+	if err := tx.QueryRow("SELECT locationId FROM holidays WHERE id = ?;", id).Scan(&locationID); err != nil {
+		return err
+	}
+
+	deleted, err := isDeleted(tx, AuditEntityLocation, locationID)
+	if err != nil {
+		return err
+	}
+
+	if deleted {
+		return ErrLocationDeleted
+	}
+
+	restored, err := markRestored(tx, hol.actor, AuditEntityHoliday, id)
+	if err != nil {
+		return err
+	}
+
+	if restored && hol.searchEnabled {
+		if err := indexHoliday(tx, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// adjustFreeSlots changes the free slots of a holiday by delta as part of a reservation change in tx.
+func (hol *HolidaysRepo) adjustFreeSlots(tx execQuerier, id int64, delta int) error {
+	before, err := snapshotRow(tx, AuditEntityHoliday, id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE holidays SET freeSlots = freeSlots + ?, version = version + 1 WHERE id = ?;", delta, id); err != nil {
+		return err
+	}
+
+	return recordAudit(tx, hol.actor, AuditEntityHoliday, id, before)
+}
+
+// sortByRating orders the best rated holidays first, holidays without ratings last.
+func sortByRating(data []HolidaysEntity) {
+	sort.SliceStable(data, func(i, j int) bool {
+		left, right := data[i].Rating, data[j].Rating
+		if left.Average == nil || right.Average == nil {
+			return left.Average != nil
+		}
+
+		if *left.Average != *right.Average {
+			return *left.Average > *right.Average
+		}
+
+		return left.Count > right.Count
+	})
+}
+
+// Facets counts the holidays per tag, country and duration bucket.
+func Facets(data []HolidaysEntity) HolidayFacets {
+	facets := HolidayFacets{
+		Tags:      map[string]int{},
+		Countries: map[string]int{},
+		Durations: map[string]int{},
+	}
+
+	for _, entity := range data {
+		for _, tag := range entity.Tags {
+			facets.Tags[tag.Name]++
+		}
+
+		facets.Countries[entity.Location.Country]++
+		facets.Durations[durationBucket(entity.Duration)]++
+	}
+
+	return facets
+}
+
+func durationBucket(days int) string {
+	switch {
+	case days <= 3:
+		return "1-3"
+	case days <= 7:
+		return "4-7"
+	case days <= 14:
+		return "8-14"
+	default:
+		return "15+"
+	}
 }
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+type IdempotencyEntity struct {
+	Caller      string
+	Key         string
+	RequestHash string
+	Status      int //? 0 while the first request is still being handled
+	Header      map[string][]string
+	Content     []byte
+	CreatedAt   string
+}
+
+// Completed reports whether the response of the first request has been stored.
+func (entity *IdempotencyEntity) Completed() bool {
+	return entity.Status != 0
+}
+
+// SetContext sets the context the statements of the repo are traced in.
+func (idem *IdempotencyRepo) SetContext(ctx context.Context) {
+	idem.db.setContext(ctx)
+}
+
+func (idem *IdempotencyRepo) traceContext() context.Context {
+	return idem.db.ctx
+}
+
+// Reserve claims the key for the caller and returns nil, or returns the stored entry when the key was used before.
+// Entries older than ttl are removed first so their keys can be used again.
+func (idem *IdempotencyRepo) Reserve(caller string, key string, requestHash string, ttl time.Duration) (*IdempotencyEntity, error) {
+	defer startSpan(idem, "IdempotencyRepo.Reserve")()
+
+	tx, err := idem.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	if _, err := tx.Exec("DELETE FROM idempotency_keys WHERE createdAt < ?;", now.Add(-ttl).Format(time.RFC3339)); err != nil {
+		return nil, err
+	}
+
+	entity := IdempotencyEntity{}
+	var header string
+	err = tx.QueryRow("SELECT caller, key, requestHash, status, header, content, createdAt FROM idempotency_keys WHERE caller = ? AND key = ?;", caller, key).
+		Scan(&entity.Caller, &entity.Key, &entity.RequestHash, &entity.Status, &header, &entity.Content, &entity.CreatedAt)
+
+	if err == nil {
+		if err := json.Unmarshal([]byte(header), &entity.Header); err != nil {
+			return nil, err
+		}
+
+		return &entity, tx.Commit()
+	}
+
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	_, err = tx.Exec("INSERT INTO idempotency_keys (caller, key, requestHash, createdAt) VALUES(?,?,?,?);", caller, key, requestHash, now.Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, tx.Commit()
+}
+
+// Complete stores the response of the request that reserved the key.
+func (idem *IdempotencyRepo) Complete(caller string, key string, status int, header map[string][]string, content []byte) error {
+	defer startSpan(idem, "IdempotencyRepo.Complete")()
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = idem.db.Exec("UPDATE idempotency_keys SET status = ?, header = ?, content = ? WHERE caller = ? AND key = ?;", status, string(headerJSON), content, caller, key)
+	return err
+}
+
+// Release removes a reserved key whose request failed so that it can be retried.
+func (idem *IdempotencyRepo) Release(caller string, key string) error {
+	defer startSpan(idem, "IdempotencyRepo.Release")()
+
+	_, err := idem.db.Exec("DELETE FROM idempotency_keys WHERE caller = ? AND key = ?;", caller, key)
+	return err
+}
@@ -0,0 +1,72 @@
+package repository
+
+// ImportRow is an entity to import together with the line it was read from.
+type ImportRow[T any] struct {
+	Line   int
+	Entity T
+}
+
+type ImportOptions struct {
+	DryRun     bool //? check every row but store none of them
+	BestEffort bool //? store the valid rows even when others fail
+}
+
+// ImportError reports why the row of a line was not imported.
+type ImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type ImportResult struct {
+	DryRun   bool          `json:"dryRun"`
+	Valid    int           `json:"valid"`    //? rows that could be stored
+	Imported int           `json:"imported"` //? rows that were stored
+	IDs      []int64       `json:"ids"`
+	Errors   []ImportError `json:"errors"`
+}
+
+// importRows inserts every row in one transaction, each behind a savepoint so a failing row
+// leaves the others untouched. Unless options.BestEffort is set a single failure stores nothing.
+func importRows[T any](db *tracedDB, rows []ImportRow[T], options ImportOptions, insert func(tx execQuerier, entity T) (int64, error)) (*ImportResult, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result := ImportResult{
+		DryRun: options.DryRun,
+		IDs:    []int64{},
+		Errors: []ImportError{},
+	}
+
+	for _, row := range rows {
+		if _, err := tx.Exec("SAVEPOINT import_row;"); err != nil {
+			return nil, err
+		}
+
+		id, err := insert(tx, row.Entity)
+		if err != nil {
+			if _, err := tx.Exec("ROLLBACK TO import_row;"); err != nil {
+				return nil, err
+			}
+
+			result.Errors = append(result.Errors, ImportError{Line: row.Line, Error: err.Error()})
+		} else {
+			result.IDs = append(result.IDs, id)
+		}
+
+		if _, err := tx.Exec("RELEASE import_row;"); err != nil {
+			return nil, err
+		}
+	}
+
+	result.Valid = len(result.IDs)
+	if options.DryRun || (!options.BestEffort && len(result.Errors) > 0) {
+		result.IDs = []int64{}
+		return &result, nil
+	}
+
+	result.Imported = len(result.IDs)
+	return &result, tx.Commit()
+}
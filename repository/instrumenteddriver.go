@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"time"
+	"travelagency/metrics"
+	"travelagency/tracing"
+
+	"github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// driverName is the SQLite driver wrapped so that every statement is measured and traced.
+const driverName = "sqlite3_instrumented"
+
+func init() {
+	sql.Register(driverName, instrumentedDriver{&sqlite3.SQLiteDriver{}})
+}
+
+type instrumentedDriver struct {
+	driver.Driver
+}
+
+func (d instrumentedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &instrumentedConn{conn}, nil
+}
+
+// instrumentedConn measures the statements run on a connection, everything else is passed through.
+type instrumentedConn struct {
+	driver.Conn
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, end := startStatementSpan(ctx, query)
+	started := time.Now()
+	result, err := execer.ExecContext(ctx, query, args)
+	observeQuery(query, started, err)
+	end(err)
+	return result, err
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, end := startStatementSpan(ctx, query)
+	started := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	observeQuery(query, started, err)
+	end(err)
+	return rows, err
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+
+	return c.Conn.Prepare(query)
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, options driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, options)
+	}
+
+	return c.Conn.Begin()
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+
+	return nil
+}
+
+// startStatementSpan starts the span of a statement run within a traced request, statements outside
+// of one, like the table setup of the repos, are not traced.
+func startStatementSpan(ctx context.Context, query string) (context.Context, func(err error)) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, func(error) {}
+	}
+
+	operation := queryOperation(query)
+	ctx, span := tracing.Tracer.Start(ctx, "sqlite "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "sqlite"),
+			attribute.String("db.operation", operation),
+			attribute.String("db.statement", query),
+		),
+	)
+
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// observeQuery records the duration of a statement and whether it failed by its operation.
+func observeQuery(query string, started time.Time, err error) {
+	operation := queryOperation(query)
+	metrics.QueryDuration.WithLabelValues(operation).Observe(time.Since(started).Seconds())
+	if err != nil {
+		metrics.QueryErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// queryOperation returns the lower case first keyword of a statement, other for anything but
+// select, insert, update and delete to keep the number of label values small.
+func queryOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "other"
+	}
+
+	switch operation := strings.ToLower(fields[0]); operation {
+	case "select", "insert", "update", "delete":
+		return operation
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,342 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var ErrImageOrderMismatch = errors.New("order must list every image of the location exactly once")
+
+type LocationImagesEntity struct {
+	ID           int64  `json:"id"`
+	LocationId   int64  `json:"locationId"`
+	Position     int    `json:"position"`
+	IsCover      bool   `json:"isCover"`
+	ContentType  string `json:"contentType"`
+	Size         int64  `json:"size"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	ImageKey     string `json:"-"` //? blob store key of the original upload
+	ThumbnailKey string `json:"-"` //? blob store key of the generated thumbnail
+	Url          string `json:"url"`
+	ThumbnailUrl string `json:"thumbnailUrl"`
+}
+
+const locationImageColumns = "id, locationId, position, isCover, contentType, size, width, height, imageKey, thumbnailKey"
+
+func scanLocationImage(row rowScanner) (*LocationImagesEntity, error) {
+	entity := LocationImagesEntity{}
+	err := row.Scan(&entity.ID, &entity.LocationId, &entity.Position, &entity.IsCover, &entity.ContentType, &entity.Size, &entity.Width, &entity.Height, &entity.ImageKey, &entity.ThumbnailKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+// SetActor sets who is recorded in the audit log for the changes made through the repo.
+func (img *LocationImagesRepo) SetActor(actor string) {
+	img.actor = actor
+}
+
+// SetContext sets the context the statements of the repo are traced in.
+func (img *LocationImagesRepo) SetContext(ctx context.Context) {
+	img.db.setContext(ctx)
+}
+
+func (img *LocationImagesRepo) traceContext() context.Context {
+	return img.db.ctx
+}
+
+// snapshotImages reads the rows of every image of a location keyed by id.
+func snapshotImages(tx execQuerier, locationID int64) (map[int64]map[string]any, error) {
+	rows, err := tx.Query("SELECT id FROM location_images WHERE locationId = ?;", locationID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	snapshots := map[int64]map[string]any{}
+	for _, id := range ids {
+		snapshots[id], err = snapshotRow(tx, AuditEntityLocationImage, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return snapshots, nil
+}
+
+// recordImagesAudit records the changes of every image that was in the before snapshot.
+func recordImagesAudit(tx execQuerier, actor string, before map[int64]map[string]any) error {
+	for id, snapshot := range before {
+		if err := recordAudit(tx, actor, AuditEntityLocationImage, id, snapshot); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Insert appends the image after the existing images of the location.
+// The first image of a location becomes its cover.
+func (img *LocationImagesRepo) Insert(entity LocationImagesEntity) (*LocationImagesEntity, error) {
+	defer startSpan(img, "LocationImagesRepo.Insert")()
+
+	img.mu.Lock()
+	defer img.mu.Unlock()
+
+	tx, err := img.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.QueryRow("SELECT COUNT(*) FROM location_images WHERE locationId = ?;", entity.LocationId).Scan(&count)
+	if err != nil {
+		return nil, err
+	}
+
+	entity.Position = count + 1
+	entity.IsCover = count == 0
+
+	resp, err := tx.Exec("INSERT INTO location_images (locationId, position, isCover, contentType, size, width, height, imageKey, thumbnailKey) VALUES(?,?,?,?,?,?,?,?,?);",
+		entity.LocationId, entity.Position, entity.IsCover, entity.ContentType, entity.Size, entity.Width, entity.Height, entity.ImageKey, entity.ThumbnailKey)
+	if err != nil {
+		return nil, err
+	}
+
+	entity.ID, err = resp.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(tx, img.actor, AuditEntityLocationImage, entity.ID, nil); err != nil {
+		return nil, err
+	}
+
+	return &entity, tx.Commit()
+}
+
+func (img *LocationImagesRepo) GetByLocationID(locationID int64) ([]LocationImagesEntity, error) {
+	defer startSpan(img, "LocationImagesRepo.GetByLocationID")()
+
+	rows, err := img.db.Query("SELECT "+locationImageColumns+" FROM location_images WHERE locationId = ? ORDER BY position;", locationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []LocationImagesEntity{}
+	for rows.Next() {
+		entity, err := scanLocationImage(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, *entity)
+	}
+
+	return data, nil
+}
+
+func (img *LocationImagesRepo) GetByID(locationID int64, id int64) (*LocationImagesEntity, error) {
+	defer startSpan(img, "LocationImagesRepo.GetByID")()
+
+	row := img.db.QueryRow("SELECT "+locationImageColumns+" FROM location_images WHERE locationId = ? AND id = ?;", locationID, id)
+
+	entity, err := scanLocationImage(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return entity, err
+}
+
+// Reorder sets the positions of the images of a location to the order of imageIDs.
+func (img *LocationImagesRepo) Reorder(locationID int64, imageIDs []int64) error {
+	defer startSpan(img, "LocationImagesRepo.Reorder")()
+
+	img.mu.Lock()
+	defer img.mu.Unlock()
+
+	tx, err := img.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotImages(tx, locationID)
+	if err != nil {
+		return err
+	}
+
+	for position, id := range imageIDs {
+		resp, err := tx.Exec("UPDATE location_images SET position = ? WHERE locationId = ? AND id = ?;", position+1, locationID, id)
+		if err != nil {
+			return err
+		}
+
+		if affected, _ := resp.RowsAffected(); affected != 1 {
+			return ErrImageOrderMismatch
+		}
+	}
+
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM location_images WHERE locationId = ?;", locationID).Scan(&count); err != nil {
+		return err
+	}
+
+	if count != len(imageIDs) {
+		return ErrImageOrderMismatch
+	}
+
+	if err := recordImagesAudit(tx, img.actor, before); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetCover makes the image the cover of its location and points the location imageUrl at it.
+func (img *LocationImagesRepo) SetCover(locationID int64, id int64, imageUrl string) error {
+	defer startSpan(img, "LocationImagesRepo.SetCover")()
+
+	img.mu.Lock()
+	defer img.mu.Unlock()
+
+	tx, err := img.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotImages(tx, locationID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE location_images SET isCover = (id = ?) WHERE locationId = ?;", id, locationID); err != nil {
+		return err
+	}
+
+	if err := setLocationImageUrl(tx, img.actor, locationID, imageUrl); err != nil {
+		return err
+	}
+
+	if err := recordImagesAudit(tx, img.actor, before); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// setLocationImageUrl points the imageUrl of the location at a cover as part of tx.
+func setLocationImageUrl(tx execQuerier, actor string, locationID int64, imageUrl string) error {
+	before, err := snapshotRow(tx, AuditEntityLocation, locationID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE locations SET imageUrl = ?, version = version + 1 WHERE id = ?;", imageUrl, locationID); err != nil {
+		return err
+	}
+
+	return recordAudit(tx, actor, AuditEntityLocation, locationID, before)
+}
+
+// Delete removes the image and closes the gap in the positions of the remaining images. When the image
+// was the cover the first remaining image becomes the cover, with imageUrl returning the url of its key,
+// and without one the location loses the imageUrl that pointed at the deleted image.
+func (img *LocationImagesRepo) Delete(locationID int64, id int64, imageUrl func(key string) string) error {
+	defer startSpan(img, "LocationImagesRepo.Delete")()
+
+	img.mu.Lock()
+	defer img.mu.Unlock()
+
+	tx, err := img.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var position int
+	var isCover bool
+	var imageKey string
+	err = tx.QueryRow("SELECT position, isCover, imageKey FROM location_images WHERE locationId = ? AND id = ?;", locationID, id).Scan(&position, &isCover, &imageKey)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	before, err := snapshotImages(tx, locationID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM location_images WHERE id = ?;", id); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE location_images SET position = position - 1 WHERE locationId = ? AND position > ?;", locationID, position); err != nil {
+		return err
+	}
+
+	if isCover {
+		if err := img.replaceCover(tx, locationID, imageUrl(imageKey), imageUrl); err != nil {
+			return err
+		}
+	}
+
+	if err := recordImagesAudit(tx, img.actor, before); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// replaceCover promotes the first image of the location after its cover at deletedUrl was deleted.
+func (img *LocationImagesRepo) replaceCover(tx execQuerier, locationID int64, deletedUrl string, imageUrl func(key string) string) error {
+	var nextID int64
+	var nextKey string
+	err := tx.QueryRow("SELECT id, imageKey FROM location_images WHERE locationId = ? ORDER BY position LIMIT 1;", locationID).Scan(&nextID, &nextKey)
+	if err == sql.ErrNoRows {
+		//? an imageUrl set by hand to another url is kept
+		var current string
+		if err := tx.QueryRow("SELECT imageUrl FROM locations WHERE id = ?;", locationID).Scan(&current); err != nil {
+			return err
+		}
+
+		if current != deletedUrl {
+			return nil
+		}
+
+		return setLocationImageUrl(tx, img.actor, locationID, "")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE location_images SET isCover = (id = ?) WHERE locationId = ?;", nextID, locationID); err != nil {
+		return err
+	}
+
+	return setLocationImageUrl(tx, img.actor, locationID, imageUrl(nextKey))
+}
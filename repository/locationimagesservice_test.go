@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"testing"
+)
+
+func TestDeletingTheCoverPromotesTheNextImageOrClearsTheLocation(t *testing.T) {
+	useTestDB(t)
+
+	locations, err := NewLocationsRepo(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	images, err := NewLocationImagesRepo(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imageUrl := func(key string) string { return "/media/" + key }
+
+	location, err := locations.Insert(LocationsEntity{City: "Lisbon", Country: "Portugal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []int64{}
+	for _, key := range []string{"first", "second"} {
+		image, err := images.Insert(LocationImagesEntity{LocationId: location.ID, ContentType: "image/png", ImageKey: key, ThumbnailKey: key + "-thumbnail"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids = append(ids, image.ID)
+	}
+
+	if err := images.SetCover(location.ID, ids[0], imageUrl("first")); err != nil {
+		t.Fatal(err)
+	}
+
+	imageUrlOf := func() string {
+		t.Helper()
+
+		current, err := locations.GetByID(location.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return current.ImageUrl
+	}
+
+	if err := images.Delete(location.ID, ids[0], imageUrl); err != nil {
+		t.Fatal(err)
+	}
+
+	next, err := images.GetByID(location.ID, ids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !next.IsCover || imageUrlOf() != "/media/second" {
+		t.Errorf("the next image is not the cover, the location points at %q", imageUrlOf())
+	}
+
+	//? without images left the location must not point at the deleted blob
+	if err := images.Delete(location.ID, ids[1], imageUrl); err != nil {
+		t.Fatal(err)
+	}
+
+	if url := imageUrlOf(); url != "" {
+		t.Errorf("the location points at %q after its last image was deleted", url)
+	}
+}
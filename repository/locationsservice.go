@@ -1,54 +1,240 @@
 package repository
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+)
 
 type LocationsEntity struct {
-	ID       int64  `json:"id"`
-	Street   string `json:"street"`
-	Number   string `json:"number"`
-	City     string `json:"city"`
-	Country  string `json:"country"`
-	ImageUrl string `json:"imageUrl"`
+	ID         int64         `json:"id"`
+	Street     string        `json:"street"`
+	Number     string        `json:"number"`
+	City       string        `json:"city"`
+	Country    string        `json:"country"`
+	ImageUrl   string        `json:"imageUrl"`
+	Latitude   *float64      `json:"latitude"`
+	Longitude  *float64      `json:"longitude"`
+	Rating     RatingSummary `json:"rating"`
+	DistanceKm *float64      `json:"distanceKm,omitempty"` //? set only when searching near a point
+	DeletedAt  *string       `json:"deletedAt,omitempty"`
+	Version    int64         `json:"version"`
+
+	Images []LocationImagesEntity `json:"images,omitempty"` //? set only on the location details
+}
+
+const locationColumns = "id, street, number, city, country, imageUrl, latitude, longitude, deletedAt, version"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLocation(row rowScanner) (*LocationsEntity, error) {
+	entity := LocationsEntity{}
+	var latitude, longitude sql.NullFloat64
+	var deletedAt sql.NullString
+
+	err := row.Scan(&entity.ID, &entity.Street, &entity.Number, &entity.City, &entity.Country, &entity.ImageUrl, &latitude, &longitude, &deletedAt, &entity.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	entity.DeletedAt = scanDeletedAt(deletedAt)
+
+	if latitude.Valid && longitude.Valid {
+		entity.Latitude = &latitude.Float64
+		entity.Longitude = &longitude.Float64
+	}
+
+	return &entity, nil
+}
+
+// point returns the coordinates of the location or nil when they are not set.
+func (entity *LocationsEntity) point() *GeoPoint {
+	if entity.Latitude == nil || entity.Longitude == nil {
+		return nil
+	}
+
+	return &GeoPoint{Latitude: *entity.Latitude, Longitude: *entity.Longitude}
+}
+
+// SetActor sets who is recorded in the audit log for the changes made through the repo.
+func (loc *LocationsRepo) SetActor(actor string) {
+	loc.actor = actor
+}
+
+// SetContext sets the context the statements of the repo are traced in.
+func (loc *LocationsRepo) SetContext(ctx context.Context) {
+	loc.db.setContext(ctx)
+}
+
+func (loc *LocationsRepo) traceContext() context.Context {
+	return loc.db.ctx
 }
 
 func (loc *LocationsRepo) Insert(entity LocationsEntity) (*LocationsEntity, error) {
+	defer startSpan(loc, "LocationsRepo.Insert")()
+
 	loc.mu.Lock()
 	defer loc.mu.Unlock()
 
-	resp, err := loc.db.Exec("INSERT INTO locations VALUES(NULL,?,?,?,?,?);", entity.Street, entity.Number, entity.City, entity.Country, entity.ImageUrl)
+	tx, err := loc.db.Begin()
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	id, err := resp.LastInsertId()
+	id, err := loc.insert(tx, entity)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
 	return &LocationsEntity{
-		ID:       id,
-		Street:   entity.Street,
-		Number:   entity.Number,
-		City:     entity.City,
-		Country:  entity.Country,
-		ImageUrl: entity.ImageUrl,
+		ID:        id,
+		Street:    entity.Street,
+		Number:    entity.Number,
+		City:      entity.City,
+		Country:   entity.Country,
+		ImageUrl:  entity.ImageUrl,
+		Latitude:  entity.Latitude,
+		Longitude: entity.Longitude,
+		Version:   1,
 	}, nil
 }
 
+// insert stores the location as part of tx and returns its id.
+func (loc *LocationsRepo) insert(tx execQuerier, entity LocationsEntity) (int64, error) {
+	resp, err := tx.Exec("INSERT INTO locations (street, number, city, country, imageUrl, latitude, longitude) VALUES(?,?,?,?,?,?,?);", entity.Street, entity.Number, entity.City, entity.Country, entity.ImageUrl, entity.Latitude, entity.Longitude)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := resp.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, recordAudit(tx, loc.actor, AuditEntityLocation, id, nil)
+}
+
+// Import stores the rows in one transaction, see importRows for the options.
+func (loc *LocationsRepo) Import(rows []ImportRow[LocationsEntity], options ImportOptions) (*ImportResult, error) {
+	defer startSpan(loc, "LocationsRepo.Import")()
+
+	loc.mu.Lock()
+	defer loc.mu.Unlock()
+
+	return importRows(loc.db, rows, options, loc.insert)
+}
+
 func (loc *LocationsRepo) Update(entity LocationsEntity) (*LocationsEntity, error) {
+	defer startSpan(loc, "LocationsRepo.Update")()
+
 	loc.mu.Lock()
 	defer loc.mu.Unlock()
 
-	_, err := loc.db.Exec("UPDATE locations SET street=?, number=?, city=?, country=?, imageUrl=? WHERE id = ?;", entity.Street, entity.Number, entity.City, entity.Country, entity.ImageUrl, entity.ID)
+	tx, err := loc.db.Begin()
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
+	before, err := snapshotRow(tx, AuditEntityLocation, entity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkVersion(tx, AuditEntityLocation, entity.ID, entity.Version); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec("UPDATE locations SET street=?, number=?, city=?, country=?, imageUrl=?, latitude=?, longitude=?, version = version + 1 WHERE id = ?;", entity.Street, entity.Number, entity.City, entity.Country, entity.ImageUrl, entity.Latitude, entity.Longitude, entity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if loc.searchEnabled {
+		if err := indexLocation(tx, entity.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := recordAudit(tx, loc.actor, AuditEntityLocation, entity.ID, before); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	entity.Version++
 	return &entity, nil
 }
 
-func (loc *LocationsRepo) GetAll() ([]LocationsEntity, error) {
-	rows, err := loc.db.Query("SELECT * FROM locations;")
+// GetAll returns the locations, soft deleted ones only when includeDeleted is set.
+func (loc *LocationsRepo) GetAll(includeDeleted bool) ([]LocationsEntity, error) {
+	defer startSpan(loc, "LocationsRepo.GetAll")()
+
+	data := []LocationsEntity{}
+	err := loc.Each(includeDeleted, func(entity LocationsEntity) error {
+		data = append(data, entity)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Each calls fn with the locations of GetAll one at a time in id order so they can be streamed.
+func (loc *LocationsRepo) Each(includeDeleted bool, fn func(entity LocationsEntity) error) error {
+	defer startSpan(loc, "LocationsRepo.Each")()
+
+	query := "SELECT " + locationColumns + " FROM locations"
+	if !includeDeleted {
+		query += " WHERE deletedAt IS NULL"
+	}
+
+	rows, err := loc.db.Query(query + " ORDER BY id;")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entity, err := scanLocation(rows)
+		if err != nil {
+			return err
+		}
+
+		entity.Rating, err = locationRating(loc.db, entity.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(*entity); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// GetNear returns the locations within radiusKm of center ordered by distance, closest first.
+func (loc *LocationsRepo) GetNear(center GeoPoint, radiusKm float64, includeDeleted bool) ([]LocationsEntity, error) {
+	defer startSpan(loc, "LocationsRepo.GetNear")()
+
+	condition, args := boundingBoxFor(center, radiusKm).sqlCondition("latitude", "longitude")
+	if !includeDeleted {
+		condition += " AND deletedAt IS NULL "
+	}
+
+	rows, err := loc.db.Query("SELECT "+locationColumns+" FROM locations WHERE latitude IS NOT NULL AND longitude IS NOT NULL"+condition+";", args...)
 	if err != nil {
 		return nil, err
 	}
@@ -56,35 +242,118 @@ func (loc *LocationsRepo) GetAll() ([]LocationsEntity, error) {
 
 	data := []LocationsEntity{}
 	for rows.Next() {
-		entity := LocationsEntity{}
-		err = rows.Scan(&entity.ID, &entity.Street, &entity.Number, &entity.City, &entity.Country, &entity.ImageUrl)
+		entity, err := scanLocation(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		data = append(data, entity)
+		distance := distanceKm(center, *entity.point())
+		if distance > radiusKm {
+			continue
+		}
+
+		entity.Rating, err = locationRating(loc.db, entity.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		entity.DistanceKm = &distance
+		data = append(data, *entity)
 	}
 
+	sortByDistance(data, func(entity LocationsEntity) float64 { return *entity.DistanceKm })
 	return data, nil
 }
 
 func (loc *LocationsRepo) GetByID(id int64) (*LocationsEntity, error) {
-	row := loc.db.QueryRow("SELECT * FROM locations WHERE id = ?;", id)
+	defer startSpan(loc, "LocationsRepo.GetByID")()
 
-	var err error
-	entity := LocationsEntity{}
+	row := loc.db.QueryRow("SELECT "+locationColumns+" FROM locations WHERE id = ?;", id)
 
-	if err = row.Scan(&entity.ID, &entity.Street, &entity.Number, &entity.City, &entity.Country, &entity.ImageUrl); err == sql.ErrNoRows {
+	entity, err := scanLocation(row)
+	if err != nil {
 		return nil, err
 	}
 
-	return &entity, nil
+	entity.Rating, err = locationRating(loc.db, entity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return entity, nil
 }
 
-func (loc *LocationsRepo) Delete(id int64) error {
+// Delete soft deletes the location when it is still at version. A location with holidays is only deleted
+// when cascade is set, its holidays are then soft deleted with it unless one of them has reservations.
+func (loc *LocationsRepo) Delete(id int64, version int64, cascade bool) error {
+	defer startSpan(loc, "LocationsRepo.Delete")()
+
 	loc.mu.Lock()
 	defer loc.mu.Unlock()
 
-	_, err := loc.db.Exec("DELETE FROM locations WHERE id = ?;", id)
-	return err
+	tx, err := loc.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkVersion(tx, AuditEntityLocation, id, version); err != nil {
+		return err
+	}
+
+	holidayIDs, err := activeChildIDs(tx, "holidays", "locationId", id)
+	if err != nil {
+		return err
+	}
+
+	if len(holidayIDs) > 0 && !cascade {
+		return ErrLocationHasHolidays
+	}
+
+	for _, holidayID := range holidayIDs {
+		reservationIDs, err := activeChildIDs(tx, "reservations", "holidayId", holidayID)
+		if err != nil {
+			return err
+		}
+
+		if len(reservationIDs) > 0 {
+			return ErrHolidayHasReservations
+		}
+
+		if err := markDeleted(tx, loc.actor, AuditEntityHoliday, holidayID); err != nil {
+			return err
+		}
+
+		if loc.searchEnabled {
+			if err := removeHolidayFromIndex(tx, holidayID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := markDeleted(tx, loc.actor, AuditEntityLocation, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Restore undoes the soft delete of the location. Holidays deleted with it stay deleted.
+func (loc *LocationsRepo) Restore(id int64) error {
+	defer startSpan(loc, "LocationsRepo.Restore")()
+
+	loc.mu.Lock()
+	defer loc.mu.Unlock()
+
+	tx, err := loc.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := markRestored(tx, loc.actor, AuditEntityLocation, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
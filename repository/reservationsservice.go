@@ -1,6 +1,10 @@
 package repository
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+	"travelagency/metrics"
+)
 
 type ReservationsEntity struct {
 	ID          int64          `json:"id"`
@@ -8,13 +12,71 @@ type ReservationsEntity struct {
 	PhoneNumber string         `json:"phoneNumber"`
 	HolidayId   int64          `json:"-"` //? used only to query the holiday entity from db
 	Holiday     HolidaysEntity `json:"holiday"`
+	DeletedAt   *string        `json:"deletedAt,omitempty"`
+	Version     int64          `json:"version"`
+}
+
+const reservationColumns = "id, contactName, phoneNumber, holidayId, deletedAt, version"
+
+func scanReservation(row rowScanner) (*ReservationsEntity, error) {
+	entity := ReservationsEntity{}
+	var deletedAt sql.NullString
+
+	if err := row.Scan(&entity.ID, &entity.ContactName, &entity.PhoneNumber, &entity.HolidayId, &deletedAt, &entity.Version); err != nil {
+		return nil, err
+	}
+
+	entity.DeletedAt = scanDeletedAt(deletedAt)
+	return &entity, nil
+}
+
+// ensureHolidayActive returns ErrHolidayDeleted when the holiday is soft deleted.
+func ensureHolidayActive(tx execQuerier, holidayID int64) error {
+	deleted, err := isDeleted(tx, AuditEntityHoliday, holidayID)
+	if err != nil {
+		return err
+	}
+
+	if deleted {
+		return ErrHolidayDeleted
+	}
+
+	return nil
+}
+
+// SetActor sets who is recorded in the audit log for the changes made through the repo.
+func (res *ReservationsRepo) SetActor(actor string) {
+	res.actor = actor
+	res.holidayRepo.SetActor(actor)
+}
+
+// SetContext sets the context the statements of the repo are traced in.
+func (res *ReservationsRepo) SetContext(ctx context.Context) {
+	res.db.setContext(ctx)
+	res.holidayRepo.SetContext(ctx)
+}
+
+func (res *ReservationsRepo) traceContext() context.Context {
+	return res.db.ctx
 }
 
 func (res *ReservationsRepo) Insert(entity ReservationsEntity) (*ReservationsEntity, error) {
+	defer startSpan(res, "ReservationsRepo.Insert")()
+
 	res.mu.Lock()
 	defer res.mu.Unlock()
 
-	resp, err := res.db.Exec("INSERT INTO reservations VALUES(NULL,?,?,?);", entity.ContactName, entity.PhoneNumber, entity.HolidayId)
+	tx, err := res.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := ensureHolidayActive(tx, entity.HolidayId); err != nil {
+		return nil, err
+	}
+
+	resp, err := tx.Exec("INSERT INTO reservations (contactName, phoneNumber, holidayId) VALUES(?,?,?);", entity.ContactName, entity.PhoneNumber, entity.HolidayId)
 	if err != nil {
 		return nil, err
 	}
@@ -24,11 +86,27 @@ func (res *ReservationsRepo) Insert(entity ReservationsEntity) (*ReservationsEnt
 		return nil, err
 	}
 
+	if err := recordAudit(tx, res.actor, AuditEntityReservation, id, nil); err != nil {
+		return nil, err
+	}
+
+	//? update free slots of holiday
+	if err := res.holidayRepo.adjustFreeSlots(tx, entity.HolidayId, -1); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	metrics.ReservationsCreated.Inc()
+
 	responseData := ReservationsEntity{
 		ID:          id,
 		ContactName: entity.ContactName,
 		PhoneNumber: entity.PhoneNumber,
 		HolidayId:   entity.HolidayId,
+		Version:     1,
 	}
 
 	holidayEntity, err := res.holidayRepo.GetByID(responseData.HolidayId)
@@ -36,42 +114,57 @@ func (res *ReservationsRepo) Insert(entity ReservationsEntity) (*ReservationsEnt
 		return nil, err
 	}
 
-	//? update free slots of holiday
-	holidayEntity.FreeSlots -= 1
-	_, err = res.holidayRepo.Update(*holidayEntity)
-	if err != nil {
-		return nil, err
-	}
-
 	responseData.Holiday = *holidayEntity
 	return &responseData, nil
 }
 
 func (res *ReservationsRepo) Update(entity ReservationsEntity) (*ReservationsEntity, error) {
+	defer startSpan(res, "ReservationsRepo.Update")()
+
 	res.mu.Lock()
 	defer res.mu.Unlock()
 
-	oldEntity, err := res.GetById(entity.ID)
+	tx, err := res.db.Begin()
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	//? if we change the holiday we need to update free slots of old holiday
-	if oldEntity.HolidayId != entity.HolidayId {
-		oldHolidayEntity, err := res.holidayRepo.GetByID(oldEntity.HolidayId)
-		if err != nil {
+	before, err := snapshotRow(tx, AuditEntityReservation, entity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkVersion(tx, AuditEntityReservation, entity.ID, entity.Version); err != nil {
+		return nil, err
+	}
+
+	if err := ensureHolidayActive(tx, entity.HolidayId); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec("UPDATE reservations SET contactName = ?, phoneNumber = ?, holidayId = ?, version = version + 1 WHERE id = ?;", entity.ContactName, entity.PhoneNumber, entity.HolidayId, entity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(tx, res.actor, AuditEntityReservation, entity.ID, before); err != nil {
+		return nil, err
+	}
+
+	//? if we change the holiday we need to move the slot from the old holiday to the new one
+	oldHolidayId := before["holidayId"].(int64)
+	if oldHolidayId != entity.HolidayId {
+		if err := res.holidayRepo.adjustFreeSlots(tx, oldHolidayId, 1); err != nil {
 			return nil, err
 		}
 
-		oldHolidayEntity.FreeSlots += 1
-		_, err = res.holidayRepo.Update(*oldHolidayEntity)
-		if err != nil {
+		if err := res.holidayRepo.adjustFreeSlots(tx, entity.HolidayId, -1); err != nil {
 			return nil, err
 		}
 	}
 
-	_, err = res.db.Exec("UPDATE reservations SET contactName = ?, phoneNumber = ?, holidayId = ? WHERE id = ?;", entity.ContactName, entity.PhoneNumber, entity.HolidayId, entity.ID)
-	if err != nil {
+	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 
@@ -80,19 +173,21 @@ func (res *ReservationsRepo) Update(entity ReservationsEntity) (*ReservationsEnt
 		return nil, err
 	}
 
-	//? update free slots of holiday
-	holidayEntity.FreeSlots -= 1
-	_, err = res.holidayRepo.Update(*holidayEntity)
-	if err != nil {
-		return nil, err
-	}
-
 	entity.Holiday = *holidayEntity
+	entity.Version++
 	return &entity, nil
 }
 
-func (res *ReservationsRepo) GetAll() ([]ReservationsEntity, error) {
-	rows, err := res.db.Query("SELECT * FROM reservations")
+// GetAll returns the reservations, soft deleted ones only when includeDeleted is set.
+func (res *ReservationsRepo) GetAll(includeDeleted bool) ([]ReservationsEntity, error) {
+	defer startSpan(res, "ReservationsRepo.GetAll")()
+
+	query := "SELECT " + reservationColumns + " FROM reservations"
+	if !includeDeleted {
+		query += " WHERE deletedAt IS NULL"
+	}
+
+	rows, err := res.db.Query(query + ";")
 	if err != nil {
 		return nil, err
 	}
@@ -100,8 +195,7 @@ func (res *ReservationsRepo) GetAll() ([]ReservationsEntity, error) {
 
 	data := []ReservationsEntity{}
 	for rows.Next() {
-		entity := ReservationsEntity{}
-		err = rows.Scan(&entity.ID, &entity.ContactName, &entity.PhoneNumber, &entity.HolidayId)
+		entity, err := scanReservation(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -112,18 +206,19 @@ func (res *ReservationsRepo) GetAll() ([]ReservationsEntity, error) {
 		}
 
 		entity.Holiday = *holidayEntity
-		data = append(data, entity)
+		data = append(data, *entity)
 	}
 
 	return data, nil
 }
 
 func (res *ReservationsRepo) GetById(id int64) (*ReservationsEntity, error) {
-	row := res.db.QueryRow("SELECT * FROM reservations WHERE id = ?", id)
+	defer startSpan(res, "ReservationsRepo.GetById")()
 
-	var err error
-	entity := ReservationsEntity{}
-	if err = row.Scan(&entity.ID, &entity.ContactName, &entity.PhoneNumber, &entity.HolidayId); err == sql.ErrNoRows {
+	row := res.db.QueryRow("SELECT "+reservationColumns+" FROM reservations WHERE id = ?;", id)
+
+	entity, err := scanReservation(row)
+	if err != nil {
 		return nil, err
 	}
 
@@ -133,26 +228,81 @@ func (res *ReservationsRepo) GetById(id int64) (*ReservationsEntity, error) {
 	}
 
 	entity.Holiday = *holidayEntity
-	return &entity, nil
+	return entity, nil
 }
 
-func (res *ReservationsRepo) Delete(id int64) error {
+// Delete soft deletes the reservation when it is still at version and gives its slot back to the holiday.
+func (res *ReservationsRepo) Delete(id int64, version int64) error {
+	defer startSpan(res, "ReservationsRepo.Delete")()
+
 	res.mu.Lock()
 	defer res.mu.Unlock()
 
-	reservationEntity, err := res.GetById(id)
+	tx, err := res.db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	before, err := snapshotRow(tx, AuditEntityReservation, id)
+	if err != nil {
+		return err
+	}
+
+	if err := checkVersion(tx, AuditEntityReservation, id, version); err != nil {
+		return err
+	}
+
+	if err := markDeleted(tx, res.actor, AuditEntityReservation, id); err != nil {
+		return err
+	}
 
 	//? update free slots of holiday
-	holidayEntity := reservationEntity.Holiday
-	holidayEntity.FreeSlots += 1
-	_, err = res.holidayRepo.Update(holidayEntity)
+	if err := res.holidayRepo.adjustFreeSlots(tx, before["holidayId"].(int64), 1); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	metrics.ReservationsCancelled.Inc()
+	return nil
+}
+
+// Restore undoes the soft delete of the reservation and takes a slot of its holiday again.
+// The holiday has to be restored first.
+func (res *ReservationsRepo) Restore(id int64) error {
+	defer startSpan(res, "ReservationsRepo.Restore")()
+
+	res.mu.Lock()
+	defer res.mu.Unlock()
+
+	tx, err := res.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var holidayID int64
+	if err := tx.QueryRow("SELECT holidayId FROM reservations WHERE id = ?;", id).Scan(&holidayID); err != nil {
+		return err
+	}
+
+	if err := ensureHolidayActive(tx, holidayID); err != nil {
+		return err
+	}
+
+	restored, err := markRestored(tx, res.actor, AuditEntityReservation, id)
 	if err != nil {
 		return err
 	}
 
-	_, err = res.db.Exec("DELETE FROM reservations WHERE id = ?", id)
-	return err
+	if restored {
+		if err := res.holidayRepo.adjustFreeSlots(tx, holidayID, -1); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"database/sql"
+	_ "embed"
+	"os"
+	"path/filepath"
+)
+
+//go:embed fixtures.sql
+var fixtures string
+
+// SnapshotDB writes a consistent copy of the database to file with VACUUM INTO while the database
+// stays in use.
+func SnapshotDB(file string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("VACUUM INTO ?;", file)
+	return err
+}
+
+// ResetDB snapshots the database into snapshotDir, recreates it and seeds the fixtures when seed is
+// set. It returns the snapshot file, empty when there was no database to keep.
+func ResetDB(snapshotDir string, seed bool) (string, error) {
+	snapshotMu.Lock()
+	defer snapshotMu.Unlock()
+
+	var snapshot string
+	if _, err := os.Stat(databaseFile); err == nil {
+		snapshot = filepath.Join(snapshotDir, snapshotFileName(resetPrefix))
+		if err := SnapshotDB(snapshot); err != nil {
+			return "", err
+		}
+	}
+
+	if err := RestartDB(); err != nil {
+		return snapshot, err
+	}
+
+	if !seed {
+		return snapshot, nil
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return snapshot, err
+	}
+
+	return snapshot, seedFixtures(db)
+}
+
+func seedFixtures(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(fixtures); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	//? the fixtures are inserted directly, index them like the repos would
+	if searchIndexReady.Load() {
+		return rebuildSearchIndex(db)
+	}
+
+	return nil
+}
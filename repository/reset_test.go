@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// useTestDB points the shared pool at a new database in a temporary directory.
+func useTestDB(t *testing.T) {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "test.db")
+	previousFile, previousDSN := databaseFile, connectionString
+	Configure(file, "file:"+file+"?_foreign_keys=1&_busy_timeout=5000&_txlock=immediate")
+	t.Cleanup(func() {
+		CloseDB()
+		Configure(previousFile, previousDSN)
+	})
+
+	if err := CreateDB(); err != nil {
+		t.Fatalf("creating the database: %v", err)
+	}
+}
+
+// clearedTables are emptied by clearDB, children before the tables they reference.
+var clearedTables = []string{
+	"reviews",
+	"reservations",
+	"holiday_tags",
+	"location_images",
+	"holidays",
+	"locations",
+	"categories",
+	"tags",
+	"audit_log",
+	"idempotency_keys",
+}
+
+// clearDB deletes every row in one transaction and seeds the fixtures when seed is set, so tests can
+// reset the state between cases without recreating the file. It is kept out of the server because
+// it empties the append-only audit log.
+func clearDB(seed bool) error {
+	snapshotMu.Lock()
+	defer snapshotMu.Unlock()
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	//? the triggers would abort deleting the audit log, they are back before the commit
+	if _, err := tx.Exec("DROP TRIGGER IF EXISTS audit_log_no_update; DROP TRIGGER IF EXISTS audit_log_no_delete;"); err != nil {
+		return err
+	}
+
+	for _, table := range clearedTables {
+		if _, err := tx.Exec("DELETE FROM " + table + ";"); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(createAuditLogTriggers); err != nil {
+		return err
+	}
+
+	if searchIndexReady.Load() {
+		if _, err := tx.Exec("DELETE FROM search_index;"); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if !seed {
+		return nil
+	}
+
+	return seedFixtures(db)
+}
+
+func TestClearDBEmptiesAuditedTables(t *testing.T) {
+	useTestDB(t)
+
+	if err := clearDB(true); err != nil {
+		t.Fatalf("seeding: %v", err)
+	}
+
+	categories, err := NewCategoriesRepo(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	categories.SetActor("alice")
+
+	//? an audited write fills the append-only audit log
+	if _, err := categories.Insert(CategoriesEntity{Name: "Cruises"}); err != nil {
+		t.Fatalf("inserting: %v", err)
+	}
+
+	db, err := openDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var audited int
+	if err := db.QueryRow("SELECT COUNT(*) FROM audit_log;").Scan(&audited); err != nil || audited == 0 {
+		t.Fatalf("expected audit log entries, got %d ( %v )", audited, err)
+	}
+
+	if err := clearDB(false); err != nil {
+		t.Fatalf("clearing: %v", err)
+	}
+
+	for _, table := range clearedTables {
+		var count int
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + table + ";").Scan(&count); err != nil {
+			t.Fatalf("counting %s: %v", table, err)
+		}
+
+		if count != 0 {
+			t.Errorf("%s has %d rows after clearDB", table, count)
+		}
+	}
+
+	//? the log is append-only again afterwards
+	if _, err := categories.Insert(CategoriesEntity{Name: "Safaris"}); err != nil {
+		t.Fatalf("inserting: %v", err)
+	}
+
+	if _, err := db.Exec("DELETE FROM audit_log;"); err == nil {
+		t.Error("deleting from audit_log succeeded after clearDB")
+	}
+}
@@ -0,0 +1,291 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	ReviewStatusPending  = "pending"
+	ReviewStatusApproved = "approved"
+	ReviewStatusRejected = "rejected"
+)
+
+var (
+	ErrReservationNotFound        = errors.New("reservation not found")
+	ErrReviewerMismatch           = errors.New("phone number does not match the reservation")
+	ErrReservationNotCompleted    = errors.New("the holiday of the reservation has not ended yet")
+	ErrReservationAlreadyReviewed = errors.New("the reservation already has a review")
+)
+
+type ReviewsEntity struct {
+	ID            int64  `json:"id"`
+	ReservationId int64  `json:"reservationId"`
+	HolidayId     int64  `json:"holidayId"`
+	Rating        int    `json:"rating"`
+	Text          string `json:"text"`
+	Status        string `json:"status"`
+	CreatedAt     string `json:"createdAt"`
+}
+
+type ReviewsFilter struct {
+	HolidayId  int64
+	LocationId int64
+	Status     string //? all statuses when empty
+}
+
+// RatingSummary aggregates the approved reviews, Average is nil when there are none.
+type RatingSummary struct {
+	Average *float64 `json:"average"`
+	Count   int      `json:"count"`
+}
+
+const reviewColumns = "r.id, r.reservationId, r.holidayId, r.rating, r.text, r.status, r.createdAt"
+
+func scanReview(row rowScanner) (*ReviewsEntity, error) {
+	entity := ReviewsEntity{}
+	err := row.Scan(&entity.ID, &entity.ReservationId, &entity.HolidayId, &entity.Rating, &entity.Text, &entity.Status, &entity.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+func holidayRating(db execQuerier, holidayID int64) (RatingSummary, error) {
+	row := db.QueryRow("SELECT AVG(rating), COUNT(*) FROM reviews WHERE holidayId = ? AND status = ?;", holidayID, ReviewStatusApproved)
+	return scanRating(row)
+}
+
+func locationRating(db execQuerier, locationID int64) (RatingSummary, error) {
+	row := db.QueryRow("SELECT AVG(r.rating), COUNT(*) FROM reviews r JOIN holidays h ON h.id = r.holidayId WHERE h.locationId = ? AND r.status = ?;", locationID, ReviewStatusApproved)
+	return scanRating(row)
+}
+
+func scanRating(row rowScanner) (RatingSummary, error) {
+	summary := RatingSummary{}
+	var average sql.NullFloat64
+	if err := row.Scan(&average, &summary.Count); err != nil {
+		return summary, err
+	}
+
+	if average.Valid {
+		summary.Average = &average.Float64
+	}
+
+	return summary, nil
+}
+
+// holidayEnded reports whether a holiday starting on startDate and lasting duration days is over.
+func holidayEnded(startDate string, duration int, now time.Time) bool {
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return false
+	}
+
+	return !now.Before(start.AddDate(0, 0, duration))
+}
+
+// SetActor sets who is recorded in the audit log for the changes made through the repo.
+func (rev *ReviewsRepo) SetActor(actor string) {
+	rev.actor = actor
+	rev.reservationRepo.SetActor(actor)
+}
+
+// SetContext sets the context the statements of the repo are traced in.
+func (rev *ReviewsRepo) SetContext(ctx context.Context) {
+	rev.db.setContext(ctx)
+	rev.reservationRepo.SetContext(ctx)
+}
+
+func (rev *ReviewsRepo) traceContext() context.Context {
+	return rev.db.ctx
+}
+
+// Insert stores a pending review for a reservation whose holiday has ended.
+// The phone number of the reservation proves that the reviewer travelled.
+func (rev *ReviewsRepo) Insert(entity ReviewsEntity, phoneNumber string) (*ReviewsEntity, error) {
+	defer startSpan(rev, "ReviewsRepo.Insert")()
+
+	rev.mu.Lock()
+	defer rev.mu.Unlock()
+
+	reservation, err := rev.reservationRepo.GetById(entity.ReservationId)
+	if err == sql.ErrNoRows {
+		return nil, ErrReservationNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if reservation.DeletedAt != nil {
+		return nil, ErrReservationNotFound
+	}
+
+	if reservation.PhoneNumber != phoneNumber {
+		return nil, ErrReviewerMismatch
+	}
+
+	if !holidayEnded(reservation.Holiday.StartDate, reservation.Holiday.Duration, time.Now()) {
+		return nil, ErrReservationNotCompleted
+	}
+
+	var exists bool
+	err = rev.db.QueryRow("SELECT EXISTS (SELECT 1 FROM reviews WHERE reservationId = ?);", entity.ReservationId).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+
+	if exists {
+		return nil, ErrReservationAlreadyReviewed
+	}
+
+	entity.HolidayId = reservation.Holiday.ID
+	entity.Status = ReviewStatusPending
+	entity.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	tx, err := rev.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	resp, err := tx.Exec("INSERT INTO reviews (reservationId, holidayId, rating, text, status, createdAt) VALUES(?,?,?,?,?,?);", entity.ReservationId, entity.HolidayId, entity.Rating, entity.Text, entity.Status, entity.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	entity.ID, err = resp.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(tx, rev.actor, AuditEntityReview, entity.ID, nil); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+// SetStatus moderates a review, only approved reviews count towards ratings.
+func (rev *ReviewsRepo) SetStatus(id int64, status string) (*ReviewsEntity, error) {
+	defer startSpan(rev, "ReviewsRepo.SetStatus")()
+
+	rev.mu.Lock()
+	defer rev.mu.Unlock()
+
+	tx, err := rev.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotRow(tx, AuditEntityReview, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("UPDATE reviews SET status = ? WHERE id = ?;", status, id); err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(tx, rev.actor, AuditEntityReview, id, before); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return rev.GetByID(id)
+}
+
+func (rev *ReviewsRepo) GetAll(filter ReviewsFilter) ([]ReviewsEntity, error) {
+	defer startSpan(rev, "ReviewsRepo.GetAll")()
+
+	query := "SELECT " + reviewColumns + " FROM reviews r JOIN holidays h ON h.id = r.holidayId WHERE 1=1"
+	args := []interface{}{}
+
+	if filter.HolidayId != 0 {
+		query += " AND r.holidayId = ? "
+		args = append(args, filter.HolidayId)
+	}
+
+	if filter.LocationId != 0 {
+		query += " AND h.locationId = ? "
+		args = append(args, filter.LocationId)
+	}
+
+	if filter.Status != "" {
+		query += " AND r.status = ? "
+		args = append(args, filter.Status)
+	}
+
+	query += " ORDER BY r.createdAt DESC;"
+
+	rows, err := rev.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []ReviewsEntity{}
+	for rows.Next() {
+		entity, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, *entity)
+	}
+
+	return data, nil
+}
+
+func (rev *ReviewsRepo) GetByID(id int64) (*ReviewsEntity, error) {
+	defer startSpan(rev, "ReviewsRepo.GetByID")()
+
+	row := rev.db.QueryRow("SELECT "+reviewColumns+" FROM reviews r WHERE r.id = ?;", id)
+
+	entity, err := scanReview(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return entity, err
+}
+
+func (rev *ReviewsRepo) Delete(id int64) error {
+	defer startSpan(rev, "ReviewsRepo.Delete")()
+
+	rev.mu.Lock()
+	defer rev.mu.Unlock()
+
+	tx, err := rev.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotRow(tx, AuditEntityReview, id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM reviews WHERE id = ?;", id); err != nil {
+		return err
+	}
+
+	if err := recordAudit(tx, rev.actor, AuditEntityReview, id, before); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
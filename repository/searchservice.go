@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+)
+
+// FTS5 is only compiled into go-sqlite3 with the `sqlite_fts5` build tag,
+// without it the index is skipped and searching returns ErrSearchUnavailable.
+var (
+	ErrSearchUnavailable = errors.New("full-text search is not available, build the server with -tags sqlite_fts5")
+	ErrEmptySearchQuery  = errors.New("search query is empty")
+)
+
+type SearchHighlights struct {
+	Title   string `json:"title"`
+	Street  string `json:"street"`
+	City    string `json:"city"`
+	Country string `json:"country"`
+}
+
+type SearchResult struct {
+	Holiday    HolidaysEntity   `json:"holiday"`
+	Rank       float64          `json:"rank"` //? bm25 score, lower is a better match
+	Highlights SearchHighlights `json:"highlights"`
+}
+
+// searchIndexReady is set by MigrateDB, the repos created afterwards keep the search index up to date
+// and search it while it is set.
+var searchIndexReady atomic.Bool
+
+// SearchAvailable reports whether the migrated database has a search index, it does not when the
+// server was built without FTS5.
+func SearchAvailable() bool {
+	return searchIndexReady.Load()
+}
+
+// ensureSearchIndex creates the search index when FTS5 is available and fills it if it is empty.
+// It reports whether the index can be used.
+func ensureSearchIndex(db *sql.DB) (bool, error) {
+	createStatement := `
+	CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
+		title,
+		street,
+		city,
+		country,
+		startMonth,
+		tokenize = 'unicode61 remove_diacritics 2'
+	);`
+
+	if _, err := db.Exec(createStatement); err != nil {
+		if strings.Contains(err.Error(), "no such module") {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	var needsRebuild bool
+	err := db.QueryRow("SELECT NOT EXISTS (SELECT 1 FROM search_index) AND EXISTS (SELECT 1 FROM holidays);").Scan(&needsRebuild)
+	if err != nil {
+		return false, err
+	}
+
+	if needsRebuild {
+		if err := rebuildSearchIndex(db); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func rebuildSearchIndex(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM search_index;"); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query("SELECT id FROM holidays WHERE deletedAt IS NULL;")
+	if err != nil {
+		return err
+	}
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		if err := indexHoliday(tx, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// indexHoliday replaces the search document of a holiday with its current title and location.
+func indexHoliday(db execQuerier, id int64) error {
+	if err := removeHolidayFromIndex(db, id); err != nil {
+		return err
+	}
+
+	var title, startDate, street, city, country string
+	row := db.QueryRow("SELECT h.title, h.startDate, l.street, l.city, l.country FROM holidays h JOIN locations l ON l.id = h.locationId WHERE h.id = ?;", id)
+	if err := row.Scan(&title, &startDate, &street, &city, &country); err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+
+		return err
+	}
+
+	_, err := db.Exec("INSERT INTO search_index (rowid, title, street, city, country, startMonth) VALUES(?,?,?,?,?,?);", id, title, street, city, country, startMonth(startDate))
+	return err
+}
+
+// indexLocation refreshes the search documents of every holiday at the location.
+func indexLocation(db execQuerier, locationID int64) error {
+	rows, err := db.Query("SELECT id FROM holidays WHERE locationId = ? AND deletedAt IS NULL;", locationID)
+	if err != nil {
+		return err
+	}
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		if err := indexHoliday(db, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func removeHolidayFromIndex(db execQuerier, id int64) error {
+	_, err := db.Exec("DELETE FROM search_index WHERE rowid = ?;", id)
+	return err
+}
+
+// startMonth makes the month of a holiday searchable, e.g. "2024-07-01" is indexed as "July 2024".
+func startMonth(startDate string) string {
+	date, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return ""
+	}
+
+	return date.Format("January 2006")
+}
+
+// matchExpression turns free text into an FTS5 query where every word must match as a prefix.
+func matchExpression(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, `"`+word+`"*`)
+	}
+
+	return strings.Join(terms, " ")
+}
+
+// Search returns the holidays matching the free text query and the filter, best match first.
+func (hol *HolidaysRepo) Search(text string, filter HolidaysFilter) ([]SearchResult, error) {
+	defer startSpan(hol, "HolidaysRepo.Search")()
+
+	if !hol.searchEnabled {
+		return nil, ErrSearchUnavailable
+	}
+
+	match := matchExpression(text)
+	if match == "" {
+		return nil, ErrEmptySearchQuery
+	}
+
+	condition, args := filter.conditions()
+	query := "SELECT " + holidayColumns + `,
+		bm25(search_index, 10.0, 2.0, 5.0, 5.0, 3.0) AS rank,
+		highlight(search_index, 0, '<mark>', '</mark>'),
+		highlight(search_index, 1, '<mark>', '</mark>'),
+		highlight(search_index, 2, '<mark>', '</mark>'),
+		highlight(search_index, 3, '<mark>', '</mark>')
+	FROM search_index
+	JOIN holidays h ON h.id = search_index.rowid
+	JOIN locations l ON l.id = h.locationId
+	WHERE search_index MATCH ?` + condition + " ORDER BY rank;"
+
+	rows, err := hol.db.Query(query, append([]interface{}{match}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []SearchResult{}
+	for rows.Next() {
+		result := SearchResult{}
+		entity, err := scanHoliday(rows, &result.Rank, &result.Highlights.Title, &result.Highlights.Street, &result.Highlights.City, &result.Highlights.Country)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := hol.loadRelations(entity); err != nil {
+			return nil, err
+		}
+
+		if !filter.distanceFrom(entity) {
+			continue
+		}
+
+		result.Holiday = *entity
+		data = append(data, result)
+	}
+
+	return data, nil
+}
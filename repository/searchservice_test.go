@@ -0,0 +1,80 @@
+//go:build sqlite_fts5
+
+package repository
+
+import (
+	"testing"
+)
+
+func TestSearchIndexFollowsLocationChanges(t *testing.T) {
+	useTestDB(t)
+
+	if !SearchAvailable() {
+		t.Fatal("the test database has no search index")
+	}
+
+	locations, err := NewLocationsRepo(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	holidays, err := NewHolidaysRepo(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	location, err := locations.Insert(LocationsEntity{Street: "Rua Augusta", Number: "1", City: "Porto", Country: "Portugal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	holiday, err := holidays.Insert(HolidaysEntity{Title: "Wine tasting", StartDate: "2024-07-01", Duration: 3, Price: 300, FreeSlots: 4, LocationId: location.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := func(text string) bool {
+		t.Helper()
+
+		results, err := holidays.Search(text, HolidaysFilter{})
+		if err != nil {
+			t.Fatalf("searching %q: %v", text, err)
+		}
+
+		for _, result := range results {
+			if result.Holiday.ID == holiday.ID {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if !found("Porto") || !found("July 2024") {
+		t.Fatal("the new holiday is not found by its city and month")
+	}
+
+	location.City = "Funchal"
+	location, err = locations.Update(*location)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//? indexLocation refreshes the documents of the holidays at the location
+	if !found("Funchal") {
+		t.Error("the holiday is not found by the new city of its location")
+	}
+
+	if found("Porto") {
+		t.Error("the holiday is still found by the old city of its location")
+	}
+
+	//? the cascade removes the holidays of the location from the index
+	if err := locations.Delete(location.ID, location.Version, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if found("Funchal") || found("Wine") {
+		t.Error("the holiday is still found after its location was deleted")
+	}
+}
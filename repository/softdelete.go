@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrLocationHasHolidays    = errors.New("location has holidays, delete them first or delete with cascade=true")
+	ErrHolidayHasReservations = errors.New("holiday has reservations, delete them first")
+	ErrLocationDeleted        = errors.New("location is deleted, restore it first")
+	ErrHolidayDeleted         = errors.New("holiday is deleted, restore it first")
+)
+
+// markDeleted sets deletedAt on a row that is not deleted yet and records it in the audit log.
+// It returns sql.ErrNoRows when the row does not exist.
+func markDeleted(tx execQuerier, actor string, entityType string, id int64) error {
+	before, err := snapshotRow(tx, entityType, id)
+	if err != nil {
+		return err
+	}
+
+	if before == nil {
+		return sql.ErrNoRows
+	}
+
+	if before["deletedAt"] != nil {
+		return nil
+	}
+
+	_, err = tx.Exec("UPDATE "+auditTables[entityType]+" SET deletedAt = ?, version = version + 1 WHERE id = ?;", time.Now().UTC().Format(time.RFC3339), id)
+	if err != nil {
+		return err
+	}
+
+	return recordAuditAction(tx, actor, entityType, id, before, AuditActionDelete)
+}
+
+// markRestored clears deletedAt and records it in the audit log.
+// It returns sql.ErrNoRows when the row does not exist and false when it was not deleted.
+func markRestored(tx execQuerier, actor string, entityType string, id int64) (bool, error) {
+	before, err := snapshotRow(tx, entityType, id)
+	if err != nil {
+		return false, err
+	}
+
+	if before == nil {
+		return false, sql.ErrNoRows
+	}
+
+	if before["deletedAt"] == nil {
+		return false, nil
+	}
+
+	_, err = tx.Exec("UPDATE "+auditTables[entityType]+" SET deletedAt = NULL, version = version + 1 WHERE id = ?;", id)
+	if err != nil {
+		return false, err
+	}
+
+	return true, recordAuditAction(tx, actor, entityType, id, before, AuditActionRestore)
+}
+
+// isDeleted reports whether the row is soft deleted, missing rows are left to the foreign keys.
+func isDeleted(tx execQuerier, entityType string, id int64) (bool, error) {
+	var deleted bool
+	err := tx.QueryRow("SELECT deletedAt IS NOT NULL FROM "+auditTables[entityType]+" WHERE id = ?;", id).Scan(&deleted)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+
+	return deleted, err
+}
+
+func scanDeletedAt(deletedAt sql.NullString) *string {
+	if !deletedAt.Valid {
+		return nil
+	}
+
+	return &deletedAt.String
+}
+
+// activeChildIDs returns the ids of the rows of table that reference parentID in column and are not soft deleted.
+func activeChildIDs(tx execQuerier, table string, column string, parentID int64) ([]int64, error) {
+	rows, err := tx.Query("SELECT id FROM "+table+" WHERE "+column+" = ? AND deletedAt IS NULL;", parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+type TagsEntity struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// SetActor sets who is recorded in the audit log for the changes made through the repo.
+func (tag *TagsRepo) SetActor(actor string) {
+	tag.actor = actor
+}
+
+// SetContext sets the context the statements of the repo are traced in.
+func (tag *TagsRepo) SetContext(ctx context.Context) {
+	tag.db.setContext(ctx)
+}
+
+func (tag *TagsRepo) traceContext() context.Context {
+	return tag.db.ctx
+}
+
+func (tag *TagsRepo) Insert(entity TagsEntity) (*TagsEntity, error) {
+	defer startSpan(tag, "TagsRepo.Insert")()
+
+	tag.mu.Lock()
+	defer tag.mu.Unlock()
+
+	tx, err := tag.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	resp, err := tx.Exec("INSERT INTO tags (name) VALUES(?);", entity.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := resp.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(tx, tag.actor, AuditEntityTag, id, nil); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &TagsEntity{
+		ID:   id,
+		Name: entity.Name,
+	}, nil
+}
+
+func (tag *TagsRepo) Update(entity TagsEntity) (*TagsEntity, error) {
+	defer startSpan(tag, "TagsRepo.Update")()
+
+	tag.mu.Lock()
+	defer tag.mu.Unlock()
+
+	tx, err := tag.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotRow(tx, AuditEntityTag, entity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if before == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	_, err = tx.Exec("UPDATE tags SET name = ? WHERE id = ?;", entity.Name, entity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(tx, tag.actor, AuditEntityTag, entity.ID, before); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+func (tag *TagsRepo) GetAll() ([]TagsEntity, error) {
+	defer startSpan(tag, "TagsRepo.GetAll")()
+
+	rows, err := tag.db.Query("SELECT id, name FROM tags ORDER BY name;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []TagsEntity{}
+	for rows.Next() {
+		entity := TagsEntity{}
+		err = rows.Scan(&entity.ID, &entity.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, entity)
+	}
+
+	return data, nil
+}
+
+func (tag *TagsRepo) GetByID(id int64) (*TagsEntity, error) {
+	defer startSpan(tag, "TagsRepo.GetByID")()
+
+	row := tag.db.QueryRow("SELECT id, name FROM tags WHERE id = ?;", id)
+
+	entity := TagsEntity{}
+	if err := row.Scan(&entity.ID, &entity.Name); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+// GetByHolidayID returns the tags of a holiday ordered by name.
+func (tag *TagsRepo) GetByHolidayID(holidayID int64) ([]TagsEntity, error) {
+	defer startSpan(tag, "TagsRepo.GetByHolidayID")()
+
+	rows, err := tag.db.Query("SELECT t.id, t.name FROM tags t JOIN holiday_tags ht ON ht.tagId = t.id WHERE ht.holidayId = ? ORDER BY t.name;", holidayID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []TagsEntity{}
+	for rows.Next() {
+		entity := TagsEntity{}
+		err = rows.Scan(&entity.ID, &entity.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, entity)
+	}
+
+	return data, nil
+}
+
+func (tag *TagsRepo) Delete(id int64) error {
+	defer startSpan(tag, "TagsRepo.Delete")()
+
+	tag.mu.Lock()
+	defer tag.mu.Unlock()
+
+	tx, err := tag.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotRow(tx, AuditEntityTag, id)
+	if err != nil {
+		return err
+	}
+
+	if before == nil {
+		return sql.ErrNoRows
+	}
+
+	if _, err := tx.Exec("DELETE FROM tags WHERE id = ?;", id); err != nil {
+		return err
+	}
+
+	if err := recordAudit(tx, tag.actor, AuditEntityTag, id, before); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// setHolidayTags replaces the tags of a holiday with tagIDs.
+func setHolidayTags(db execQuerier, holidayID int64, tagIDs []int64) error {
+	if _, err := db.Exec("DELETE FROM holiday_tags WHERE holidayId = ?;", holidayID); err != nil {
+		return err
+	}
+
+	for _, tagID := range tagIDs {
+		if _, err := db.Exec("INSERT OR IGNORE INTO holiday_tags (holidayId, tagId) VALUES(?,?);", holidayID, tagID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"travelagency/tracing"
+)
+
+// tracedDB runs the statements of a repo with the context set by SetContext, so that they are
+// traced as part of the request the repo serves.
+type tracedDB struct {
+	*sql.DB
+	ctx context.Context
+}
+
+func newTracedDB(db *sql.DB) *tracedDB {
+	return &tracedDB{DB: db, ctx: context.Background()}
+}
+
+// setContext keeps the trace of ctx but not its cancellation, a client that goes away does not
+// abort the statements of a change halfway.
+func (db *tracedDB) setContext(ctx context.Context) {
+	db.ctx = context.WithoutCancel(ctx)
+}
+
+func (db *tracedDB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.DB.ExecContext(db.ctx, query, args...)
+}
+
+func (db *tracedDB) Query(query string, args ...any) (*sql.Rows, error) {
+	return db.DB.QueryContext(db.ctx, query, args...)
+}
+
+func (db *tracedDB) QueryRow(query string, args ...any) *sql.Row {
+	return db.DB.QueryRowContext(db.ctx, query, args...)
+}
+
+func (db *tracedDB) Begin() (*tracedTx, error) {
+	tx, err := db.DB.BeginTx(db.ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tracedTx{Tx: tx, ctx: db.ctx}, nil
+}
+
+// tracedTx is a transaction whose statements keep the context it was started with.
+type tracedTx struct {
+	*sql.Tx
+	ctx context.Context
+}
+
+func (tx *tracedTx) Exec(query string, args ...any) (sql.Result, error) {
+	return tx.Tx.ExecContext(tx.ctx, query, args...)
+}
+
+func (tx *tracedTx) Query(query string, args ...any) (*sql.Rows, error) {
+	return tx.Tx.QueryContext(tx.ctx, query, args...)
+}
+
+func (tx *tracedTx) QueryRow(query string, args ...any) *sql.Row {
+	return tx.Tx.QueryRowContext(tx.ctx, query, args...)
+}
+
+// tracedRepo is implemented by every repo, SetContext also sets the context of the repos it uses.
+type tracedRepo interface {
+	SetContext(ctx context.Context)
+	traceContext() context.Context
+}
+
+// startSpan starts the span of a repo method, the statements and nested repo calls made until the
+// returned function is called become its children.
+func startSpan(repo tracedRepo, name string) func() {
+	parent := repo.traceContext()
+	ctx, span := tracing.Tracer.Start(parent, name)
+	repo.SetContext(ctx)
+
+	return func() {
+		repo.SetContext(parent)
+		span.End()
+	}
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var ErrVersionMismatch = errors.New("the entity was changed since it was read")
+
+// checkVersion returns ErrVersionMismatch unless the row is still at the expected version.
+// It returns sql.ErrNoRows when the row does not exist or is soft deleted.
+func checkVersion(tx execQuerier, entityType string, id int64, expected int64) error {
+	var version int64
+	var deletedAt sql.NullString
+
+	err := tx.QueryRow("SELECT version, deletedAt FROM "+auditTables[entityType]+" WHERE id = ?;", id).Scan(&version, &deletedAt)
+	if err != nil {
+		return err
+	}
+
+	if deletedAt.Valid {
+		return sql.ErrNoRows
+	}
+
+	if version != expected {
+		return ErrVersionMismatch
+	}
+
+	return nil
+}
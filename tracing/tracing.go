@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+const serviceName = "travelagency"
+
+// Tracer starts the spans of the API and the repository, it uses the provider installed by Setup.
+// Use replaces it, the global tracers only ever delegate to the first provider installed.
+var Tracer = otel.Tracer(serviceName)
+
+// Setup installs the W3C trace context propagator and the exporter:
+// `otlp` ( configured with the standard OTEL_EXPORTER_OTLP_* variables ), `console` for stdout or
+// `none`, the default, to only pass the trace context on. The returned function flushes the spans.
+func Setup(ctx context.Context, exporterName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch exporterName {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	case "console", "stdout":
+		exporter, err = stdouttrace.New()
+	default:
+		return nil, fmt.Errorf("unknown traces exporter %q, use otlp, console or none", exporterName)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Use(sdktrace.WithBatcher(exporter)), nil
+}
+
+// Use installs a tracer provider with the options, like a span processor recording spans in memory,
+// and returns the function that shuts it down.
+func Use(options ...sdktrace.TracerProviderOption) func(context.Context) error {
+	serviceResource, _ := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(serviceName)))
+
+	provider := sdktrace.NewTracerProvider(append([]sdktrace.TracerProviderOption{sdktrace.WithResource(serviceResource)}, options...)...)
+	otel.SetTracerProvider(provider)
+	Tracer = provider.Tracer(serviceName)
+	return provider.Shutdown
+}